@@ -0,0 +1,259 @@
+// Package audit keeps a trail of every notification. Hooks are called
+// before and after each send with a normalized Event, and Record writes
+// those events to a Sink, such as a JSON lines file or a SQL table.
+//
+//	sink, err := audit.OpenFile("/var/log/notif/audit.jsonl")
+//	bellHandler = interceptor.NewBell(bellHandler, audit.Interceptor(audit.Record(sink)))
+//
+// Events hold recipients as they were sent, unmasked.
+package audit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/bell"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/interceptor"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/mailer"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/oca"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/whatsapp"
+)
+
+// Outcomes of a send.
+const (
+	OutcomePending = "pending"
+	OutcomeSent    = "sent"
+	OutcomeFailed  = "failed"
+)
+
+// Event is a send, before or after it happened.
+type Event struct {
+	// ID is shared by the events of the same send.
+	ID string `json:"id"`
+	// Channel is one of the sandbox.Channel constants.
+	Channel string `json:"channel"`
+	// Method is one of the interceptor.Method constants.
+	Method     string   `json:"method"`
+	Recipients []string `json:"recipients"`
+	// Template is the template code of emails and OCA messages, or the type
+	// of bell notifications and WhatsApp messages. It is empty for emails
+	// whose TemplateCode holds their body.
+	Template string `json:"template,omitempty"`
+	// Outcome is OutcomePending before the send and OutcomeSent or
+	// OutcomeFailed after it. Bulk sends fail when every recipient failed.
+	Outcome string `json:"outcome"`
+	// Error is the error of the send, or the errors of the recipients of a
+	// bulk send that failed.
+	Error string `json:"error,omitempty"`
+	// ProviderIDs are the message IDs found in the response of the provider.
+	ProviderIDs []string  `json:"provider_ids,omitempty"`
+	StartedAt   time.Time `json:"started_at"`
+	// FinishedAt is nil before the send.
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// Hook is called before and after every send. An error of BeforeSend stops
+// the send.
+type Hook interface {
+	BeforeSend(ctx context.Context, event Event) error
+	AfterSend(ctx context.Context, event Event) error
+}
+
+// ErrorHandler receives the errors of AfterSend, which do not change the
+// result of the send.
+type ErrorHandler func(ctx context.Context, event Event, err error)
+
+// LogErrors returns an ErrorHandler logging to logger, or slog.Default()
+// when nil. Recipients are left out of the log.
+func LogErrors(logger *slog.Logger) ErrorHandler {
+	return func(ctx context.Context, event Event, err error) {
+		l := logger
+		if l == nil {
+			l = slog.Default()
+		}
+		l.ErrorContext(ctx, "audit hook failed", "id", event.ID, "channel", event.Channel, "method", event.Method, "outcome", event.Outcome, "error", err)
+	}
+}
+
+// Interceptor returns an interceptor calling hooks around every send.
+// Errors of AfterSend are logged with slog.Default().
+func Interceptor(hooks ...Hook) interceptor.Interceptor {
+	return InterceptorWithErrorHandler(LogErrors(nil), hooks...)
+}
+
+// InterceptorWithErrorHandler is like Interceptor but hands the errors of
+// AfterSend to handle.
+func InterceptorWithErrorHandler(handle ErrorHandler, hooks ...Hook) interceptor.Interceptor {
+	return func(ctx context.Context, call *interceptor.Call, next interceptor.Handler) (interface{}, error) {
+		event := NewEvent(call)
+		for _, hook := range hooks {
+			if err := hook.BeforeSend(ctx, event); err != nil {
+				return nil, fmt.Errorf("audit: %w", err)
+			}
+		}
+
+		data, err := next(ctx, call)
+
+		finishedAt := time.Now().UTC()
+		event.FinishedAt = &finishedAt
+		event.Outcome, event.Error = outcome(data, err)
+		event.ProviderIDs = providerIDs(data)
+		for _, hook := range hooks {
+			if hookErr := hook.AfterSend(ctx, event); hookErr != nil && handle != nil {
+				handle(ctx, event, fmt.Errorf("audit: %w", hookErr))
+			}
+		}
+		return data, err
+	}
+}
+
+// outcome returns the outcome and error of a send. A bulk OCA send fails
+// when every recipient failed, and its error lists the recipients that
+// failed.
+func outcome(data interface{}, err error) (string, string) {
+	if err != nil {
+		return OutcomeFailed, err.Error()
+	}
+	bulk, ok := data.(oca.BulkResult)
+	if !ok || bulk.Failed == 0 {
+		return OutcomeSent, ""
+	}
+	var errs []string
+	for _, result := range bulk.Results {
+		if result.Status == oca.StatusFailed {
+			errs = append(errs, result.PhoneNumber+": "+result.Error)
+		}
+	}
+	if bulk.Sent == 0 {
+		return OutcomeFailed, strings.Join(errs, "\n")
+	}
+	return OutcomeSent, strings.Join(errs, "\n")
+}
+
+// NewEvent returns the pending event of call.
+func NewEvent(call *interceptor.Call) Event {
+	event := Event{
+		ID:        newID(),
+		Channel:   call.Channel,
+		Method:    call.Method,
+		Outcome:   OutcomePending,
+		StartedAt: time.Now().UTC(),
+	}
+	switch payload := call.Payload.(type) {
+	case bell.NotificationPayload:
+		event.Recipients = []string{payload.UserID}
+		event.Template = payload.Type
+	case interceptor.BellBroadcast:
+		for _, user := range payload.UserIdentifiers {
+			event.Recipients = append(event.Recipients, user.UserID)
+		}
+		for _, p := range payload.Payloads {
+			if len(payload.UserIdentifiers) == 0 {
+				event.Recipients = append(event.Recipients, p.UserID)
+			}
+			if event.Template == "" {
+				event.Template = p.Type
+			}
+		}
+	case mailer.Mail:
+		event.Recipients = append(append(append(event.Recipients, payload.To...), payload.CC...), payload.BCC...)
+		event.Template = templateName(payload.TemplateCode)
+	case interceptor.EmailWithFilePaths:
+		// Message may be the body of the mail, which stays out of the
+		// audit trail.
+		event.Recipients = payload.Mail.To
+	case oca.OCA:
+		event.Recipients = payload.PhoneNumber
+		event.Template = payload.MessageData.Template.TemplateCodeID
+	case oca.OCABulk:
+		for _, recipient := range payload.Recipients {
+			event.Recipients = append(event.Recipients, recipient.PhoneNumber)
+		}
+		event.Template = payload.MessageData.Template.TemplateCodeID
+	case whatsapp.Whatsapp:
+		event.Recipients = payload.To
+		event.Template = payload.Type
+	}
+	return event
+}
+
+// maxTemplate is the size of the template column of Schema.
+const maxTemplate = 255
+
+// templateName returns code when it names a template. Mails sent over
+// SMTP without HTMLBody carry their whole body in TemplateCode, which
+// stays out of the audit trail.
+func templateName(code string) string {
+	if len(code) > maxTemplate || strings.ContainsAny(code, "<> \t\r\n") {
+		return ""
+	}
+	return code
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// providerIDs returns the message IDs of a send result: those returned by
+// a ProviderIDs method, or else the values of message_id, message_ids and
+// provider_id fields.
+func providerIDs(data interface{}) []string {
+	if data == nil {
+		return nil
+	}
+	if d, ok := data.(interface{ ProviderIDs() []string }); ok {
+		return d.ProviderIDs()
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil
+	}
+	var v interface{}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return nil
+	}
+	var ids []string
+	collectIDs(v, &ids)
+	return ids
+}
+
+func collectIDs(v interface{}, ids *[]string) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			switch key {
+			case "message_id", "message_ids", "provider_id":
+				collectValues(value, ids)
+			default:
+				collectIDs(value, ids)
+			}
+		}
+	case []interface{}:
+		for _, value := range v {
+			collectIDs(value, ids)
+		}
+	}
+}
+
+func collectValues(v interface{}, ids *[]string) {
+	switch v := v.(type) {
+	case string:
+		if v != "" {
+			*ids = append(*ids, v)
+		}
+	case float64:
+		*ids = append(*ids, fmt.Sprint(v))
+	case []interface{}:
+		for _, value := range v {
+			collectValues(value, ids)
+		}
+	}
+}
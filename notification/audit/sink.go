@@ -0,0 +1,72 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+)
+
+type Sink interface {
+	Write(ctx context.Context, event Event) error
+}
+
+type recorder struct {
+	sink Sink
+}
+
+// Record returns a hook writing the events before and after every send to
+// sink.
+func Record(sink Sink) Hook {
+	return recorder{sink: sink}
+}
+
+func (r recorder) BeforeSend(ctx context.Context, event Event) error {
+	return r.sink.Write(ctx, event)
+}
+
+func (r recorder) AfterSend(ctx context.Context, event Event) error {
+	return r.sink.Write(ctx, event)
+}
+
+// JSONLines writes events as JSON, one per line.
+type JSONLines struct {
+	mu     sync.Mutex
+	w      io.Writer
+	closer io.Closer
+}
+
+// NewJSONLines returns a JSONLines writing to w.
+func NewJSONLines(w io.Writer) *JSONLines {
+	return &JSONLines{w: w}
+}
+
+// OpenFile returns a JSONLines appending to the file at path, created
+// when missing.
+func OpenFile(path string) (*JSONLines, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &JSONLines{w: f, closer: f}, nil
+}
+
+func (j *JSONLines) Write(ctx context.Context, event Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	_, err = j.w.Write(append(line, '\n'))
+	return err
+}
+
+// Close closes the file opened by OpenFile.
+func (j *JSONLines) Close() error {
+	if j.closer == nil {
+		return nil
+	}
+	return j.closer.Close()
+}
@@ -0,0 +1,83 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Schema creates the table SQL writes to, named audit_events. Recipients
+// and provider IDs are stored as JSON arrays.
+const Schema = `CREATE TABLE IF NOT EXISTS audit_events (
+	id           VARCHAR(32)  NOT NULL,
+	channel      VARCHAR(32)  NOT NULL,
+	method       VARCHAR(64)  NOT NULL,
+	recipients   TEXT         NOT NULL,
+	template     VARCHAR(255) NOT NULL,
+	outcome      VARCHAR(16)  NOT NULL,
+	error        TEXT         NOT NULL,
+	provider_ids TEXT         NOT NULL,
+	started_at   TIMESTAMP    NOT NULL,
+	finished_at  TIMESTAMP    NULL
+)`
+
+// SQL inserts events as rows of a table, one per event, so a send has a
+// pending row and a sent or failed row.
+type SQL struct {
+	DB *sql.DB
+	// Table defaults to audit_events.
+	Table string
+	// Placeholder returns the placeholder of the n-th argument, from 1.
+	// Defaults to "?"; use Dollar for PostgreSQL.
+	Placeholder func(n int) string
+}
+
+// Dollar returns PostgreSQL placeholders, e.g. $1.
+func Dollar(n int) string {
+	return fmt.Sprintf("$%d", n)
+}
+
+func (s *SQL) Write(ctx context.Context, event Event) error {
+	recipients, err := json.Marshal(nonNil(event.Recipients))
+	if err != nil {
+		return err
+	}
+	providerIDs, err := json.Marshal(nonNil(event.ProviderIDs))
+	if err != nil {
+		return err
+	}
+	var finishedAt sql.NullTime
+	if event.FinishedAt != nil {
+		finishedAt = sql.NullTime{Time: *event.FinishedAt, Valid: true}
+	}
+	_, err = s.DB.ExecContext(ctx, s.insert(),
+		event.ID, event.Channel, event.Method, string(recipients), event.Template,
+		event.Outcome, event.Error, string(providerIDs), event.StartedAt, finishedAt,
+	)
+	return err
+}
+
+func (s *SQL) insert() string {
+	table := s.Table
+	if table == "" {
+		table = "audit_events"
+	}
+	placeholder := s.Placeholder
+	if placeholder == nil {
+		placeholder = func(int) string { return "?" }
+	}
+	placeholders := make([]string, 10)
+	for i := range placeholders {
+		placeholders[i] = placeholder(i + 1)
+	}
+	return "INSERT INTO " + table + " (id, channel, method, recipients, template, outcome, error, provider_ids, started_at, finished_at) VALUES (" + strings.Join(placeholders, ", ") + ")"
+}
+
+func nonNil(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
+}
@@ -5,35 +5,57 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"sync"
 	"time"
 
 	cfg "github.com/DamiaRalitsa/notif-lib-golang/notification/config"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/httpstatus"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/internal/report"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/metrics"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/option"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/sandbox"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/tracing"
 )
 
 type gatewayApi struct {
 	FabdBaseUrl string
 	ApiKey      string
+	HTTPClient  *http.Client
+	Sandbox     *sandbox.Sandbox
+	Logger      *slog.Logger
+	Metrics     metrics.Metrics
+	Tracer      *tracing.Tracer
 }
 
-func NewNotifBellApiHandler() (NotifBellClient, error) {
+func NewNotifBellApiHandler(opts ...option.Option) (NotifBellClient, error) {
 	config, err := cfg.InitEnv(cfg.API)
 	if err != nil {
 		return nil, err
 	}
+	o, err := option.New(opts...)
+	if err != nil {
+		return nil, err
+	}
 	g := &gatewayApi{
-		FabdBaseUrl: config.ApiConfig.FabdBaseUrl,
+		FabdBaseUrl: o.BaseURLOr(config.ApiConfig.FabdBaseUrl),
 		ApiKey:      config.ApiConfig.ApiKey,
+		HTTPClient:  o.HTTPClient,
+		Sandbox:     o.Sandbox,
+		Logger:      o.Logger,
+		Metrics:     o.Metrics,
+		Tracer:      o.Tracer,
 	}
 	return g, err
 }
 
-func (g *gatewayApi) SendBell(ctx context.Context, payload NotificationPayload) error {
+func (g *gatewayApi) SendBell(ctx context.Context, payload NotificationPayload) (err error) {
+	ctx, span := g.Tracer.Start(ctx, "bell.SendBell", sandbox.ChannelBell, tracing.RecipientsKey.Int(1))
 	start := time.Now()
 	defer func() {
-		log.Printf("sendNotif took %v", time.Since(start))
+		g.Tracer.End(span, err)
+		report.Send(ctx, g.Logger, g.Metrics, sandbox.ChannelBell, g.FabdBaseUrl+"/v4/webhooks/notifications", 1, start, err, "method", "SendBell")
 	}()
 
 	var wg sync.WaitGroup
@@ -61,9 +83,9 @@ func (g *gatewayApi) SendBell(ctx context.Context, payload NotificationPayload)
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		if err := g.pushNotif(payload); err != nil {
+		if err := g.pushNotif(ctx, payload); err != nil {
 			select {
-			case errChan <- fmt.Errorf("failed to send bell notifications: %v", err):
+			case errChan <- fmt.Errorf("failed to send bell notifications: %w", err):
 			default:
 			}
 		}
@@ -81,28 +103,31 @@ func (g *gatewayApi) SendBell(ctx context.Context, payload NotificationPayload)
 	return nil
 }
 
-func (g *gatewayApi) SendBellBroadcast(ctx context.Context, userIdentifiers []UserIdentifier, payloads []NotificationPayload) error {
+func (g *gatewayApi) SendBellBroadcast(ctx context.Context, userIdentifiers []UserIdentifier, payloads []NotificationPayload) (err error) {
+	recipients := len(userIdentifiers)
+	if recipients == 0 {
+		recipients = len(payloads)
+	}
+	ctx, span := g.Tracer.Start(ctx, "bell.SendBellBroadcast", sandbox.ChannelBell, tracing.RecipientsKey.Int(recipients))
 	start := time.Now()
 	defer func() {
-		log.Printf("sendNotif took %v", time.Since(start))
+		g.Tracer.End(span, err)
+		report.Send(ctx, g.Logger, g.Metrics, sandbox.ChannelBell, g.FabdBaseUrl+"/v4/webhooks/notifications-bulk", recipients, start, err, "method", "SendBellBroadcast")
 	}()
 
 	if len(userIdentifiers) == 0 {
 		for _, payload := range payloads {
 			if err := validatePayload(payload); err != nil {
-				log.Printf("Validation error: %v", err)
-				return fmt.Errorf("validation error: %v", err)
+				g.Logger.DebugContext(ctx, "invalid bell notification payload", "error", err)
+				return fmt.Errorf("validation error: %w", err)
 			}
 		}
 
-		log.Printf("Prepared %d notification payloads", len(payloads))
+		g.Logger.DebugContext(ctx, "prepared bell notification payloads", "count", len(payloads))
 
-		pushStart := time.Now()
-		if err := g.pushNotifBulk(payloads); err != nil {
-			log.Printf("Error sending notifications: %v", err)
-			return fmt.Errorf("failed to send broadcast notifications")
+		if err := g.pushNotifBulk(ctx, payloads); err != nil {
+			return fmt.Errorf("failed to send broadcast notifications: %w", err)
 		}
-		log.Printf("pushNotifBulk took %v", time.Since(pushStart))
 
 		return nil
 	}
@@ -120,7 +145,7 @@ func (g *gatewayApi) SendBellBroadcast(ctx context.Context, userIdentifiers []Us
 			notificationPayload.UserID = user.UserID
 
 			if err := validatePayload(notificationPayload); err != nil {
-				log.Printf("Validation error for user %s: %v", user.UserID, err)
+				g.Logger.DebugContext(ctx, "invalid bell notification payload", "user_id", user.UserID, "error", err)
 				errChan <- err
 				return
 			}
@@ -140,14 +165,11 @@ func (g *gatewayApi) SendBellBroadcast(ctx context.Context, userIdentifiers []Us
 		payloadList = append(payloadList, payload)
 	}
 
-	log.Printf("Prepared %d notification payloads", len(payloadList))
+	g.Logger.DebugContext(ctx, "prepared bell notification payloads", "count", len(payloadList))
 
-	pushStart := time.Now()
-	if err := g.pushNotifBulk(payloadList); err != nil {
-		log.Printf("Error sending notifications: %v", err)
-		return fmt.Errorf("failed to send broadcast notifications")
+	if err := g.pushNotifBulk(ctx, payloadList); err != nil {
+		return fmt.Errorf("failed to send broadcast notifications: %w", err)
 	}
-	log.Printf("pushNotifBulk took %v", time.Since(pushStart))
 
 	for err := range errChan {
 		if err != nil {
@@ -158,52 +180,65 @@ func (g *gatewayApi) SendBellBroadcast(ctx context.Context, userIdentifiers []Us
 	return nil
 }
 
-func (g *gatewayApi) pushNotif(payload NotificationPayload) error {
+func (g *gatewayApi) pushNotif(ctx context.Context, payload NotificationPayload) error {
 	url := g.FabdBaseUrl + "/v4/webhooks/notifications"
+	if g.Sandbox != nil {
+		payload.UserID = g.Sandbox.UserID(payload.UserID)
+		return g.Sandbox.Capture(ctx, sandbox.Record{
+			Channel:    sandbox.ChannelBell,
+			Endpoint:   url,
+			Recipients: []string{payload.UserID},
+			Payload:    payload,
+		})
+	}
 	jsonData, err := json.Marshal(payload)
 	if err != nil {
 		return err
 	}
 
-	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return err
 	}
 	req.Header.Set("Authorization", g.ApiKey)
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
+	client := g.HTTPClient
 	resp, err := client.Do(req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
-	log.Println("Response from external endpoint:", resp.Status)
-	return nil
+	g.Logger.DebugContext(ctx, "response from external endpoint", "channel", sandbox.ChannelBell, "endpoint", url, "status", resp.StatusCode)
+	return httpstatus.Check(resp, nil)
 }
 
-func (g *gatewayApi) pushNotifBulk(payload []NotificationPayload) error {
+func (g *gatewayApi) pushNotifBulk(ctx context.Context, payload []NotificationPayload) error {
 	url := g.FabdBaseUrl + "/v4/webhooks/notifications-bulk"
+	g.Metrics.Batch(sandbox.ChannelBell, url, len(payload))
+	if g.Sandbox != nil {
+		return g.Sandbox.Capture(ctx, sandboxBulkRecord(g.Sandbox, url, payload))
+	}
 	jsonData, err := json.Marshal(payload)
 	if err != nil {
 		return err
 	}
 
-	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return err
 	}
 	req.Header.Set("Authorization", g.ApiKey)
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
+	client := g.HTTPClient
 	resp, err := client.Do(req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
-	log.Println("Response from external endpoint:", resp.Status)
-	return nil
+	g.Logger.DebugContext(ctx, "response from external endpoint", "channel", sandbox.ChannelBell, "endpoint", url, "status", resp.StatusCode)
+	return httpstatus.Check(resp, nil)
 }
@@ -6,35 +6,57 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"log"
+	"log/slog"
 	"net/http"
 	"sync"
 	"time"
 
 	cfg "github.com/DamiaRalitsa/notif-lib-golang/notification/config"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/httpstatus"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/internal/report"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/metrics"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/option"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/sandbox"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/tracing"
 )
 
 type gateway struct {
 	FabdBaseUrl string
 	ApiKey      string
+	HTTPClient  *http.Client
+	Sandbox     *sandbox.Sandbox
+	Logger      *slog.Logger
+	Metrics     metrics.Metrics
+	Tracer      *tracing.Tracer
 }
 
-func NewNotifBellHandler() (NotifBellClient, error) {
+func NewNotifBellHandler(opts ...option.Option) (NotifBellClient, error) {
 	config, err := cfg.InitEnv(cfg.BELL)
 	if err != nil {
 		return nil, err
 	}
+	o, err := option.New(opts...)
+	if err != nil {
+		return nil, err
+	}
 	g := &gateway{
-		FabdBaseUrl: config.BellConfig.FabdBaseUrl,
+		FabdBaseUrl: o.BaseURLOr(config.BellConfig.FabdBaseUrl),
 		ApiKey:      config.BellConfig.ApiKey,
+		HTTPClient:  o.HTTPClient,
+		Sandbox:     o.Sandbox,
+		Logger:      o.Logger,
+		Metrics:     o.Metrics,
+		Tracer:      o.Tracer,
 	}
 	return g, err
 }
 
-func (g *gateway) SendBell(ctx context.Context, payload NotificationPayload) error {
+func (g *gateway) SendBell(ctx context.Context, payload NotificationPayload) (err error) {
+	ctx, span := g.Tracer.Start(ctx, "bell.SendBell", sandbox.ChannelBell, tracing.RecipientsKey.Int(1))
 	start := time.Now()
 	defer func() {
-		log.Printf("sendNotif took %v", time.Since(start))
+		g.Tracer.End(span, err)
+		report.Send(ctx, g.Logger, g.Metrics, sandbox.ChannelBell, g.FabdBaseUrl+"/v4/webhooks/notification", 1, start, err, "method", "SendBell")
 	}()
 
 	var wg sync.WaitGroup
@@ -62,9 +84,9 @@ func (g *gateway) SendBell(ctx context.Context, payload NotificationPayload) err
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		if err := g.pushNotif(payload); err != nil {
+		if err := g.pushNotif(ctx, payload); err != nil {
 			select {
-			case errChan <- fmt.Errorf("failed to send bell notifications: %v", err):
+			case errChan <- fmt.Errorf("failed to send bell notifications: %w", err):
 			default:
 			}
 		}
@@ -82,28 +104,31 @@ func (g *gateway) SendBell(ctx context.Context, payload NotificationPayload) err
 	return nil
 }
 
-func (g *gateway) SendBellBroadcast(ctx context.Context, userIdentifiers []UserIdentifier, payloads []NotificationPayload) error {
+func (g *gateway) SendBellBroadcast(ctx context.Context, userIdentifiers []UserIdentifier, payloads []NotificationPayload) (err error) {
+	recipients := len(userIdentifiers)
+	if recipients == 0 {
+		recipients = len(payloads)
+	}
+	ctx, span := g.Tracer.Start(ctx, "bell.SendBellBroadcast", sandbox.ChannelBell, tracing.RecipientsKey.Int(recipients))
 	start := time.Now()
 	defer func() {
-		log.Printf("sendNotif took %v", time.Since(start))
+		g.Tracer.End(span, err)
+		report.Send(ctx, g.Logger, g.Metrics, sandbox.ChannelBell, g.FabdBaseUrl+"/v4/webhooks/notifications-bulk", recipients, start, err, "method", "SendBellBroadcast")
 	}()
 
 	if len(userIdentifiers) == 0 {
 		for _, payload := range payloads {
 			if err := validatePayload(payload); err != nil {
-				log.Printf("Validation error: %v", err)
-				return fmt.Errorf("validation error: %v", err)
+				g.Logger.DebugContext(ctx, "invalid bell notification payload", "error", err)
+				return fmt.Errorf("validation error: %w", err)
 			}
 		}
 
-		log.Printf("Prepared %d notification payloads", len(payloads))
+		g.Logger.DebugContext(ctx, "prepared bell notification payloads", "count", len(payloads))
 
-		pushStart := time.Now()
-		if err := g.pushNotifBulk(payloads); err != nil {
-			log.Printf("Error sending notifications: %v", err)
-			return fmt.Errorf("failed to send broadcast notifications")
+		if err := g.pushNotifBulk(ctx, payloads); err != nil {
+			return fmt.Errorf("failed to send broadcast notifications: %w", err)
 		}
-		log.Printf("pushNotifBulk took %v", time.Since(pushStart))
 
 		return nil
 	}
@@ -121,7 +146,7 @@ func (g *gateway) SendBellBroadcast(ctx context.Context, userIdentifiers []UserI
 			notificationPayload.UserID = user.UserID
 
 			if err := validatePayload(notificationPayload); err != nil {
-				log.Printf("Validation error for user %s: %v", user.UserID, err)
+				g.Logger.DebugContext(ctx, "invalid bell notification payload", "user_id", user.UserID, "error", err)
 				errChan <- err
 				return
 			}
@@ -141,14 +166,11 @@ func (g *gateway) SendBellBroadcast(ctx context.Context, userIdentifiers []UserI
 		payloadList = append(payloadList, payload)
 	}
 
-	log.Printf("Prepared %d notification payloads", len(payloadList))
+	g.Logger.DebugContext(ctx, "prepared bell notification payloads", "count", len(payloadList))
 
-	pushStart := time.Now()
-	if err := g.pushNotifBulk(payloadList); err != nil {
-		log.Printf("Error sending notifications: %v", err)
-		return fmt.Errorf("failed to send broadcast notifications")
+	if err := g.pushNotifBulk(ctx, payloadList); err != nil {
+		return fmt.Errorf("failed to send broadcast notifications: %w", err)
 	}
-	log.Printf("pushNotifBulk took %v", time.Since(pushStart))
 
 	for err := range errChan {
 		if err != nil {
@@ -159,9 +181,18 @@ func (g *gateway) SendBellBroadcast(ctx context.Context, userIdentifiers []UserI
 	return nil
 }
 
-func (g *gateway) pushNotif(payload NotificationPayload) error {
+func (g *gateway) pushNotif(ctx context.Context, payload NotificationPayload) error {
 	url := g.FabdBaseUrl + "/v4/webhooks/notification"
-	req, err := http.NewRequest(http.MethodPost, url, nil)
+	if g.Sandbox != nil {
+		payload.UserID = g.Sandbox.UserID(payload.UserID)
+		return g.Sandbox.Capture(ctx, sandbox.Record{
+			Channel:    sandbox.ChannelBell,
+			Endpoint:   url,
+			Recipients: []string{payload.UserID},
+			Payload:    payload,
+		})
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
 	if err != nil {
 		return err
 	}
@@ -172,37 +203,41 @@ func (g *gateway) pushNotif(payload NotificationPayload) error {
 		return err
 	}
 	req.Body = ioutil.NopCloser(bytes.NewBuffer(jsonData))
-	client := &http.Client{}
+	client := g.HTTPClient
 	resp, err := client.Do(req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
-	log.Println("Response from external endpoint:", resp.Status)
-	return nil
+	g.Logger.DebugContext(ctx, "response from external endpoint", "channel", sandbox.ChannelBell, "endpoint", url, "status", resp.StatusCode)
+	return httpstatus.Check(resp, nil)
 }
 
-func (g *gateway) pushNotifBulk(payload []NotificationPayload) error {
+func (g *gateway) pushNotifBulk(ctx context.Context, payload []NotificationPayload) error {
 	url := g.FabdBaseUrl + "/v4/webhooks/notifications-bulk"
+	g.Metrics.Batch(sandbox.ChannelBell, url, len(payload))
+	if g.Sandbox != nil {
+		return g.Sandbox.Capture(ctx, sandboxBulkRecord(g.Sandbox, url, payload))
+	}
 	jsonData, err := json.Marshal(payload)
 	if err != nil {
 		return err
 	}
 
-	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return err
 	}
 	req.Header.Set("Authorization", g.ApiKey)
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
+	client := g.HTTPClient
 	resp, err := client.Do(req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
-	log.Println("Response from external endpoint:", resp.Status)
-	return nil
+	g.Logger.DebugContext(ctx, "response from external endpoint", "channel", sandbox.ChannelBell, "endpoint", url, "status", resp.StatusCode)
+	return httpstatus.Check(resp, nil)
 }
@@ -0,0 +1,21 @@
+package bell
+
+import "github.com/DamiaRalitsa/notif-lib-golang/notification/sandbox"
+
+// sandboxBulkRecord rewrites the users of a bulk request and describes it
+// for the sandbox store.
+func sandboxBulkRecord(s *sandbox.Sandbox, url string, payloads []NotificationPayload) sandbox.Record {
+	rewritten := make([]NotificationPayload, len(payloads))
+	recipients := make([]string, len(payloads))
+	for i, payload := range payloads {
+		payload.UserID = s.UserID(payload.UserID)
+		rewritten[i] = payload
+		recipients[i] = payload.UserID
+	}
+	return sandbox.Record{
+		Channel:    sandbox.ChannelBell,
+		Endpoint:   url,
+		Recipients: recipients,
+		Payload:    rewritten,
+	}
+}
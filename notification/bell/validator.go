@@ -3,6 +3,8 @@ package bell
 import (
 	"fmt"
 	"reflect"
+
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/metrics"
 )
 
 func validatePayload(payload NotificationPayload) error {
@@ -10,10 +12,10 @@ func validatePayload(payload NotificationPayload) error {
 	for i := 0; i < v.NumField(); i++ {
 		field := v.Field(i)
 		if field.Kind() == reflect.String && field.String() == "" {
-			return fmt.Errorf("missing required field: %s", v.Type().Field(i).Name)
+			return metrics.Invalid(fmt.Errorf("missing required field: %s", v.Type().Field(i).Name))
 		}
 		if field.Kind() == reflect.Interface && field.IsNil() {
-			return fmt.Errorf("missing required field: %s", v.Type().Field(i).Name)
+			return metrics.Invalid(fmt.Errorf("missing required field: %s", v.Type().Field(i).Name))
 		}
 	}
 	return nil
@@ -1,8 +1,9 @@
 package config
 
 import (
-	"log"
+	"fmt"
 	"os"
+	"strconv"
 
 	"github.com/joho/godotenv"
 	"gopkg.in/go-playground/validator.v9"
@@ -13,35 +14,78 @@ func init() {
 }
 
 const (
-	OCA           = "oca"
-	BELL          = "bell"
-	EMAIL         = "email"
-	API           = "api"
-	EnvPrefix     = "NOTIF_"
-	EmailHost     = EnvPrefix + "EMAIL_HOST"
-	EmailPort     = EnvPrefix + "EMAIL_PORT"
-	EmailUserName = EnvPrefix + "EMAIL_USERNAME"
-	EmailPassword = EnvPrefix + "EMAIL_PASSWORD"
+	OCA              = "oca"
+	BELL             = "bell"
+	EMAIL            = "email"
+	API              = "api"
+	SANDBOX          = "sandbox"
+	POLICY           = "policy"
+	WHATSAPP         = "whatsapp"
+	EnvPrefix        = "NOTIF_"
+	EmailHost        = EnvPrefix + "EMAIL_HOST"
+	EmailPort        = EnvPrefix + "EMAIL_PORT"
+	EmailUserName    = EnvPrefix + "EMAIL_USERNAME"
+	EmailPassword    = EnvPrefix + "EMAIL_PASSWORD"
+	EmailTemplateDir = EnvPrefix + "EMAIL_TEMPLATE_DIR"
+
+	EmailDKIMDomain     = EnvPrefix + "EMAIL_DKIM_DOMAIN"
+	EmailDKIMSelector   = EnvPrefix + "EMAIL_DKIM_SELECTOR"
+	EmailDKIMPrivateKey = EnvPrefix + "EMAIL_DKIM_PRIVATE_KEY"
+	EmailDKIMKeyFile    = EnvPrefix + "EMAIL_DKIM_PRIVATE_KEY_FILE"
+	EmailDKIMHeaders    = EnvPrefix + "EMAIL_DKIM_HEADERS"
 
 	OCAWABASEURL = EnvPrefix + "OCA_WA_BASE_URL"
 	OCAWAToken   = EnvPrefix + "OCA_WA_TOKEN"
 
+	WhatsappBaseURL     = EnvPrefix + "WHATSAPP_BASE_URL"
+	WhatsappAppKey      = EnvPrefix + "WHATSAPP_APP_KEY"
+	WhatsappAuthKey     = EnvPrefix + "WHATSAPP_AUTH_KEY"
+	WhatsappTemplateDir = EnvPrefix + "WHATSAPP_TEMPLATE_DIR"
+
+	Sandbox                 = EnvPrefix + "SANDBOX"
+	SandboxCatchAllEmail    = EnvPrefix + "SANDBOX_CATCH_ALL_EMAIL"
+	SandboxTestPhoneNumbers = EnvPrefix + "SANDBOX_TEST_PHONE_NUMBERS"
+	SandboxTestUserID       = EnvPrefix + "SANDBOX_TEST_USER_ID"
+
+	PolicyAllowedEmailDomains = EnvPrefix + "POLICY_ALLOWED_EMAIL_DOMAINS"
+	PolicyAllowedPhoneNumbers = EnvPrefix + "POLICY_ALLOWED_PHONE_NUMBERS"
+	PolicyAllowedUserIDs      = EnvPrefix + "POLICY_ALLOWED_USER_IDS"
+	PolicyCatchAllEmail       = EnvPrefix + "POLICY_CATCH_ALL_EMAIL"
+	PolicyCatchAllPhoneNumber = EnvPrefix + "POLICY_CATCH_ALL_PHONE_NUMBER"
+	PolicyCatchAllUserID      = EnvPrefix + "POLICY_CATCH_ALL_USER_ID"
+	PolicyEnvPrefix           = EnvPrefix + "POLICY_ENV_PREFIX"
+
 	FabdBaseUrl = EnvPrefix + "FABD_BASE_URL"
 	ApiKey      = EnvPrefix + "API_KEY"
 )
 
 type Config struct {
-	EmailConfig EmailConfig
-	OCAConfig   OCAConfig
-	BellConfig  BellConfig
-	ApiConfig   ApiConfig
+	EmailConfig    EmailConfig
+	OCAConfig      OCAConfig
+	BellConfig     BellConfig
+	ApiConfig      ApiConfig
+	SandboxConfig  SandboxConfig
+	PolicyConfig   PolicyConfig
+	WhatsappConfig WhatsappConfig
 }
 
 type EmailConfig struct {
-	EmailHost     string `json:"notif_email_host" validate:"required"`
-	EmailPort     string `json:"notif_email_port" validate:"required"`
-	EmailUserName string `json:"notif_email_username" validate:"required"`
-	EmailPassword string `json:"notif_email_password" validate:"required"`
+	EmailHost        string `json:"notif_email_host" validate:"required"`
+	EmailPort        string `json:"notif_email_port" validate:"required"`
+	EmailUserName    string `json:"notif_email_username" validate:"required"`
+	EmailPassword    string `json:"notif_email_password" validate:"required"`
+	EmailTemplateDir string `json:"notif_email_template_dir"`
+	DKIM             DKIMConfig
+}
+
+// DKIMConfig enables DKIM signing of SMTP mail when Domain is set. The key
+// is read from PrivateKey (PEM) or, when empty, from PrivateKeyFile.
+type DKIMConfig struct {
+	Domain         string `json:"notif_email_dkim_domain"`
+	Selector       string `json:"notif_email_dkim_selector" validate:"required_with=Domain"`
+	PrivateKey     string `json:"notif_email_dkim_private_key"`
+	PrivateKeyFile string `json:"notif_email_dkim_private_key_file"`
+	Headers        string `json:"notif_email_dkim_headers"`
 }
 
 type OCAConfig struct {
@@ -49,6 +93,13 @@ type OCAConfig struct {
 	OCAWAToken   string `json:"notif_oca_wa_token" validate:"required"`
 }
 
+type WhatsappConfig struct {
+	BaseURL     string `json:"notif_whatsapp_base_url" validate:"required"`
+	AppKey      string `json:"notif_whatsapp_app_key" validate:"required"`
+	AuthKey     string `json:"notif_whatsapp_auth_key" validate:"required"`
+	TemplateDir string `json:"notif_whatsapp_template_dir"`
+}
+
 type BellConfig struct {
 	FabdBaseUrl string `json:"notif_fabd_base_url" validate:"required"`
 	ApiKey      string `json:"notif_api_key" validate:"required"`
@@ -59,23 +110,60 @@ type ApiConfig struct {
 	ApiKey      string `json:"notif_api_key" validate:"required"`
 }
 
+// SandboxConfig enables the dry-run mode of every gateway. TestPhoneNumbers
+// is a comma separated list.
+type SandboxConfig struct {
+	Enabled          bool   `json:"notif_sandbox"`
+	CatchAllEmail    string `json:"notif_sandbox_catch_all_email" validate:"omitempty,email"`
+	TestPhoneNumbers string `json:"notif_sandbox_test_phone_numbers"`
+	TestUserID       string `json:"notif_sandbox_test_user_id"`
+}
+
+// PolicyConfig configures the recipient policy. The allowlists are comma
+// separated.
+type PolicyConfig struct {
+	AllowedEmailDomains string `json:"notif_policy_allowed_email_domains"`
+	AllowedPhoneNumbers string `json:"notif_policy_allowed_phone_numbers"`
+	AllowedUserIDs      string `json:"notif_policy_allowed_user_ids"`
+	CatchAllEmail       string `json:"notif_policy_catch_all_email" validate:"omitempty,email"`
+	CatchAllPhoneNumber string `json:"notif_policy_catch_all_phone_number"`
+	CatchAllUserID      string `json:"notif_policy_catch_all_user_id"`
+	EnvPrefix           string `json:"notif_policy_env_prefix"`
+}
+
 func getEnv(key string) string {
 	return os.Getenv(key)
 }
 
+// parseBool reads an optional boolean variable, rejecting what
+// strconv.ParseBool cannot read instead of treating it as false.
+func parseBool(value string) (bool, error) {
+	if value == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
+
 func InitEnv(configName string) (Config, error) {
 	config := Config{}
 	switch configName {
 	case EMAIL:
 		emailConfig := EmailConfig{
-			EmailHost:     getEnv(EmailHost),
-			EmailPort:     getEnv(EmailPort),
-			EmailUserName: getEnv(EmailUserName),
-			EmailPassword: getEnv(EmailPassword),
+			EmailHost:        getEnv(EmailHost),
+			EmailPort:        getEnv(EmailPort),
+			EmailUserName:    getEnv(EmailUserName),
+			EmailPassword:    getEnv(EmailPassword),
+			EmailTemplateDir: getEnv(EmailTemplateDir),
+			DKIM: DKIMConfig{
+				Domain:         getEnv(EmailDKIMDomain),
+				Selector:       getEnv(EmailDKIMSelector),
+				PrivateKey:     getEnv(EmailDKIMPrivateKey),
+				PrivateKeyFile: getEnv(EmailDKIMKeyFile),
+				Headers:        getEnv(EmailDKIMHeaders),
+			},
 		}
 		if err := validateEnv(&emailConfig); err != nil {
-			log.Printf("Email configuration is not valid: %v", err)
-			return Config{}, err
+			return Config{}, fmt.Errorf("email configuration is not valid: %w", err)
 		}
 		config.EmailConfig = emailConfig
 	case OCA:
@@ -84,18 +172,27 @@ func InitEnv(configName string) (Config, error) {
 			OCAWAToken:   getEnv(OCAWAToken),
 		}
 		if err := validateEnv(&ocaConfig); err != nil {
-			log.Printf("OCA configuration is not valid: %v", err)
-			return Config{}, err
+			return Config{}, fmt.Errorf("oca configuration is not valid: %w", err)
 		}
 		config.OCAConfig = ocaConfig
+	case WHATSAPP:
+		whatsappConfig := WhatsappConfig{
+			BaseURL:     getEnv(WhatsappBaseURL),
+			AppKey:      getEnv(WhatsappAppKey),
+			AuthKey:     getEnv(WhatsappAuthKey),
+			TemplateDir: getEnv(WhatsappTemplateDir),
+		}
+		if err := validateEnv(&whatsappConfig); err != nil {
+			return Config{}, fmt.Errorf("whatsapp configuration is not valid: %w", err)
+		}
+		config.WhatsappConfig = whatsappConfig
 	case BELL:
 		bellConfig := BellConfig{
 			FabdBaseUrl: getEnv(FabdBaseUrl),
 			ApiKey:      getEnv(ApiKey),
 		}
 		if err := validateEnv(&bellConfig); err != nil {
-			log.Printf("Bell configuration is not valid: %v", err)
-			return Config{}, err
+			return Config{}, fmt.Errorf("bell configuration is not valid: %w", err)
 		}
 		config.BellConfig = bellConfig
 	case API:
@@ -104,19 +201,43 @@ func InitEnv(configName string) (Config, error) {
 			ApiKey:      getEnv(ApiKey),
 		}
 		if err := validateEnv(&apiConfig); err != nil {
-			log.Printf("Api configuration is not valid: %v", err)
-			return Config{}, err
+			return Config{}, fmt.Errorf("api configuration is not valid: %w", err)
 		}
 		config.ApiConfig = apiConfig
+	case SANDBOX:
+		enabled, err := parseBool(getEnv(Sandbox))
+		if err != nil {
+			return Config{}, fmt.Errorf("sandbox configuration is not valid: %s: %w", Sandbox, err)
+		}
+		sandboxConfig := SandboxConfig{
+			Enabled:          enabled,
+			CatchAllEmail:    getEnv(SandboxCatchAllEmail),
+			TestPhoneNumbers: getEnv(SandboxTestPhoneNumbers),
+			TestUserID:       getEnv(SandboxTestUserID),
+		}
+		if err := validateEnv(&sandboxConfig); err != nil {
+			return Config{}, fmt.Errorf("sandbox configuration is not valid: %w", err)
+		}
+		config.SandboxConfig = sandboxConfig
+	case POLICY:
+		policyConfig := PolicyConfig{
+			AllowedEmailDomains: getEnv(PolicyAllowedEmailDomains),
+			AllowedPhoneNumbers: getEnv(PolicyAllowedPhoneNumbers),
+			AllowedUserIDs:      getEnv(PolicyAllowedUserIDs),
+			CatchAllEmail:       getEnv(PolicyCatchAllEmail),
+			CatchAllPhoneNumber: getEnv(PolicyCatchAllPhoneNumber),
+			CatchAllUserID:      getEnv(PolicyCatchAllUserID),
+			EnvPrefix:           getEnv(PolicyEnvPrefix),
+		}
+		if err := validateEnv(&policyConfig); err != nil {
+			return Config{}, fmt.Errorf("policy configuration is not valid: %w", err)
+		}
+		config.PolicyConfig = policyConfig
 	}
 	return config, nil
 }
 
 func validateEnv(cfg any) error {
 	validate := validator.New()
-	if err := validate.Struct(cfg); err != nil {
-		log.Fatalf("Validation failed: %v", err)
-		return err
-	}
-	return nil
+	return validate.Struct(cfg)
 }
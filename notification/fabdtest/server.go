@@ -0,0 +1,360 @@
+// Package fabdtest provides a fake FABD server for testing the bell, mailer
+// API and OCA API gateways without network access. It records every request,
+// decodes it into the library's model types and can script failures and
+// latency per endpoint.
+//
+//	srv := fabdtest.NewServer()
+//	defer srv.Close()
+//	srv.Setenv(t)
+//
+//	client, _ := bell.NewNotifBellApiHandler()
+//	client.SendBell(ctx, payload)
+//
+//	notifications := srv.BellNotifications()
+package fabdtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/bell"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/config"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/mailer"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/oca"
+)
+
+// Endpoints served by the fake, relative to the base URL.
+const (
+	PathBell     = "/v4/webhooks/notification"
+	PathBellAPI  = "/v4/webhooks/notifications"
+	PathBellBulk = "/v4/webhooks/notifications-bulk"
+	PathEmail    = "/v4/webhooks/email-notifications"
+	PathWhatsapp = "/v4/webhooks/whatsapp-notification"
+)
+
+// DefaultAPIKey is the API key accepted by a new Server.
+const DefaultAPIKey = "fabdtest-api-key"
+
+const (
+	maxFormMemory  = 32 << 20
+	successMessage = "success"
+)
+
+// Request is a recorded request. Exactly one of the decoded fields is set
+// when the body could be decoded for the endpoint.
+type Request struct {
+	Method string
+	Path   string
+	Header http.Header
+	Body   []byte
+	Time   time.Time
+
+	Bell     *bell.NotificationPayload
+	BellBulk []bell.NotificationPayload
+	Email    *mailer.Mail
+	Whatsapp *oca.OCA
+	// DecodeErr is set when the body did not match the endpoint's model.
+	DecodeErr error
+}
+
+// Response scripts the reply to a request. A zero Status means 200.
+type Response struct {
+	Status int
+	Body   string
+	Delay  time.Duration
+}
+
+type Server struct {
+	*httptest.Server
+	// APIKey is the Authorization value the server accepts. Requests with
+	// another value get a 401. Empty disables the check.
+	APIKey string
+
+	mu        sync.Mutex
+	requests  []Request
+	scripted  map[string][]Response
+	latency   time.Duration
+	overrides map[string]Response
+}
+
+// NewServer starts a fake FABD server accepting DefaultAPIKey.
+func NewServer() *Server {
+	s := &Server{
+		APIKey:    DefaultAPIKey,
+		scripted:  make(map[string][]Response),
+		overrides: make(map[string]Response),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Env sets environment variables, like testing.TB does for the duration of
+// a test.
+type Env interface {
+	Setenv(key, value string)
+}
+
+// Setenv points the FABD configuration at the server so the API
+// constructors connect to it.
+func (s *Server) Setenv(env Env) {
+	env.Setenv(config.FabdBaseUrl, s.URL)
+	env.Setenv(config.ApiKey, s.APIKey)
+}
+
+// Script queues responses for path, used in order by the next requests.
+// Once exhausted the endpoint answers successfully again.
+func (s *Server) Script(path string, responses ...Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scripted[path] = append(s.scripted[path], responses...)
+}
+
+// FailNext makes the next n requests to path fail with status.
+func (s *Server) FailNext(path string, n, status int) {
+	responses := make([]Response, n)
+	for i := range responses {
+		responses[i] = Response{Status: status, Body: fmt.Sprintf(`{"status":false,"message":%q}`, http.StatusText(status))}
+	}
+	s.Script(path, responses...)
+}
+
+// SetResponse makes every request to path answer with r until cleared with
+// ClearResponse.
+func (s *Server) SetResponse(path string, r Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.overrides[path] = r
+}
+
+// ClearResponse removes the response set with SetResponse.
+func (s *Server) ClearResponse(path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.overrides, path)
+}
+
+// SetLatency delays every response by d.
+func (s *Server) SetLatency(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = d
+}
+
+// Reset clears recorded requests, scripted responses and latency.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = nil
+	s.scripted = make(map[string][]Response)
+	s.overrides = make(map[string]Response)
+	s.latency = 0
+}
+
+// Requests returns the recorded requests.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// RequestsTo returns the recorded requests to path.
+func (s *Server) RequestsTo(path string) []Request {
+	var matched []Request
+	for _, r := range s.Requests() {
+		if r.Path == path {
+			matched = append(matched, r)
+		}
+	}
+	return matched
+}
+
+// BellNotifications returns every bell notification received, flattening
+// single and bulk requests.
+func (s *Server) BellNotifications() []bell.NotificationPayload {
+	var payloads []bell.NotificationPayload
+	for _, r := range s.Requests() {
+		if r.Bell != nil {
+			payloads = append(payloads, *r.Bell)
+		}
+		payloads = append(payloads, r.BellBulk...)
+	}
+	return payloads
+}
+
+// Emails returns every email received.
+func (s *Server) Emails() []mailer.Mail {
+	var mails []mailer.Mail
+	for _, r := range s.Requests() {
+		if r.Email != nil {
+			mails = append(mails, *r.Email)
+		}
+	}
+	return mails
+}
+
+// Whatsapps returns every OCA WhatsApp request received.
+func (s *Server) Whatsapps() []oca.OCA {
+	var messages []oca.OCA
+	for _, r := range s.Requests() {
+		if r.Whatsapp != nil {
+			messages = append(messages, *r.Whatsapp)
+		}
+	}
+	return messages
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	record := Request{
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Header: r.Header.Clone(),
+		Body:   body,
+		Time:   time.Now(),
+	}
+	known := decode(&record)
+
+	s.mu.Lock()
+	s.requests = append(s.requests, record)
+	response, scripted := s.nextResponse(r.URL.Path)
+	latency := s.latency
+	apiKey := s.APIKey
+	s.mu.Unlock()
+
+	if delay := latency + response.Delay; delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case scripted:
+		status := response.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		body := response.Body
+		if body == "" {
+			body = fmt.Sprintf(`{"status":%t,"message":%q}`, status < 300, http.StatusText(status))
+		}
+		w.WriteHeader(status)
+		io.WriteString(w, body)
+	case apiKey != "" && r.Header.Get("Authorization") != apiKey:
+		w.WriteHeader(http.StatusUnauthorized)
+		io.WriteString(w, `{"status":false,"message":"unauthorized"}`)
+	case !known:
+		w.WriteHeader(http.StatusNotFound)
+		io.WriteString(w, `{"status":false,"message":"not found"}`)
+	case record.DecodeErr != nil:
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, `{"status":false,"message":%q}`, record.DecodeErr.Error())
+	default:
+		fmt.Fprintf(w, `{"status":true,"message":%q}`, successMessage)
+	}
+}
+
+// nextResponse must be called with s.mu held.
+func (s *Server) nextResponse(path string) (Response, bool) {
+	if queue := s.scripted[path]; len(queue) > 0 {
+		s.scripted[path] = queue[1:]
+		return queue[0], true
+	}
+	if r, ok := s.overrides[path]; ok {
+		return r, true
+	}
+	return Response{}, false
+}
+
+// decode fills the typed fields of r and reports whether the path is a
+// known FABD endpoint.
+func decode(r *Request) bool {
+	switch r.Path {
+	case PathBell, PathBellAPI:
+		var payload bell.NotificationPayload
+		r.DecodeErr = json.Unmarshal(r.Body, &payload)
+		if r.DecodeErr == nil {
+			r.Bell = &payload
+		}
+	case PathBellBulk:
+		r.DecodeErr = json.Unmarshal(r.Body, &r.BellBulk)
+	case PathWhatsapp:
+		var payload oca.OCA
+		r.DecodeErr = json.Unmarshal(r.Body, &payload)
+		if r.DecodeErr == nil {
+			r.Whatsapp = &payload
+		}
+	case PathEmail:
+		r.Email, r.DecodeErr = decodeEmail(r.Header.Get("Content-Type"), r.Body)
+	default:
+		return false
+	}
+	return true
+}
+
+func decodeEmail(contentType string, body []byte) (*mailer.Mail, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, err
+	}
+	if mediaType != "multipart/form-data" {
+		return nil, fmt.Errorf("unexpected content type %s", mediaType)
+	}
+	form, err := multipart.NewReader(bytes.NewReader(body), params["boundary"]).ReadForm(maxFormMemory)
+	if err != nil {
+		return nil, err
+	}
+	defer form.RemoveAll()
+
+	mail := &mailer.Mail{
+		To:           form.Value["to"],
+		CC:           form.Value["cc"],
+		BCC:          form.Value["bcc"],
+		Subject:      first(form.Value["subject"]),
+		TemplateCode: first(form.Value["template_code"]),
+		Locale:       first(form.Value["locale"]),
+	}
+	if data := first(form.Value["data"]); data != "" {
+		if err := json.Unmarshal([]byte(data), &mail.Data); err != nil {
+			return nil, fmt.Errorf("invalid data field: %v", err)
+		}
+	}
+	for _, field := range []string{"attachments", "inline_attachments"} {
+		for _, header := range form.File[field] {
+			file, err := header.Open()
+			if err != nil {
+				return nil, err
+			}
+			content, err := io.ReadAll(file)
+			file.Close()
+			if err != nil {
+				return nil, err
+			}
+			mail.Attachments = append(mail.Attachments, mailer.Attachment{
+				FileName:  header.Filename,
+				Content:   content,
+				Inline:    field == "inline_attachments",
+				ContentID: strings.Trim(header.Header.Get("Content-ID"), "<>"),
+			})
+		}
+	}
+	return mail, nil
+}
+
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
@@ -0,0 +1,49 @@
+// Package httpstatus checks the status of the responses of FABD, OCA and
+// the WhatsApp gateway. A send whose response has a non-2xx status fails
+// with an *Error:
+//
+//	var statusErr *httpstatus.Error
+//	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusTooManyRequests {
+//		// retry later
+//	}
+package httpstatus
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// maxBody limits the part of the response body kept in an Error.
+const maxBody = 4 << 10
+
+// Error is the error of a response with a non-2xx status.
+type Error struct {
+	StatusCode int
+	Status     string
+	// Body is the beginning of the response body, if it was read.
+	Body string
+}
+
+func (e *Error) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("unexpected response status %s", e.Status)
+	}
+	return fmt.Sprintf("unexpected response status %s: %s", e.Status, e.Body)
+}
+
+// Check returns an *Error when resp has a non-2xx status. body is the
+// response body when it was already read; otherwise the beginning of
+// resp.Body is read.
+func Check(resp *http.Response, body []byte) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	if body == nil {
+		body, _ = io.ReadAll(io.LimitReader(resp.Body, maxBody))
+	}
+	if len(body) > maxBody {
+		body = body[:maxBody]
+	}
+	return &Error{StatusCode: resp.StatusCode, Status: resp.Status, Body: string(body)}
+}
@@ -0,0 +1,48 @@
+package interceptor
+
+import (
+	"context"
+
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/bell"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/sandbox"
+)
+
+// BellBroadcast is the payload of SendBellBroadcast.
+type BellBroadcast struct {
+	UserIdentifiers []bell.UserIdentifier
+	Payloads        []bell.NotificationPayload
+}
+
+type interceptedBell struct {
+	client bell.NotifBellClient
+	chain  Interceptor
+}
+
+// NewBell wraps client so every notification goes through interceptors.
+func NewBell(client bell.NotifBellClient, interceptors ...Interceptor) bell.NotifBellClient {
+	return &interceptedBell{client: client, chain: Chain(interceptors...)}
+}
+
+func (b *interceptedBell) SendBell(ctx context.Context, payload bell.NotificationPayload) error {
+	call := &Call{Channel: sandbox.ChannelBell, Method: MethodSendBell, Payload: payload}
+	_, err := b.chain(ctx, call, func(ctx context.Context, call *Call) (interface{}, error) {
+		payload, ok := call.Payload.(bell.NotificationPayload)
+		if !ok {
+			return nil, payloadError(call, payload)
+		}
+		return nil, b.client.SendBell(ctx, payload)
+	})
+	return err
+}
+
+func (b *interceptedBell) SendBellBroadcast(ctx context.Context, userIdentifiers []bell.UserIdentifier, payloads []bell.NotificationPayload) error {
+	call := &Call{Channel: sandbox.ChannelBell, Method: MethodSendBellBroadcast, Payload: BellBroadcast{UserIdentifiers: userIdentifiers, Payloads: payloads}}
+	_, err := b.chain(ctx, call, func(ctx context.Context, call *Call) (interface{}, error) {
+		broadcast, ok := call.Payload.(BellBroadcast)
+		if !ok {
+			return nil, payloadError(call, broadcast)
+		}
+		return nil, b.client.SendBellBroadcast(ctx, broadcast.UserIdentifiers, broadcast.Payloads)
+	})
+	return err
+}
@@ -0,0 +1,68 @@
+// Package interceptor wraps the channel clients so every send goes through
+// a chain of interceptors. An interceptor sees the context and payload of
+// the send, may change them, and calls next to continue, or returns without
+// calling it to stop the send. It then sees the result and error.
+//
+//	audit := func(ctx context.Context, call *interceptor.Call, next interceptor.Handler) (interface{}, error) {
+//		data, err := next(ctx, call)
+//		log.Printf("%s.%s: %v", call.Channel, call.Method, err)
+//		return data, err
+//	}
+//	bellHandler = interceptor.NewBell(bellHandler, audit)
+package interceptor
+
+import (
+	"context"
+	"fmt"
+)
+
+// Methods of the wrapped clients.
+const (
+	MethodSendBell               = "SendBell"
+	MethodSendBellBroadcast      = "SendBellBroadcast"
+	MethodSendEmail              = "SendEmail"
+	MethodSendEmailWithFilePaths = "SendEmailWithFilePaths"
+	MethodSendWhatsapp           = "SendWhatsapp"
+	MethodSendWhatsappBulk       = "SendWhatsappBulk"
+)
+
+// Call is an intercepted send.
+type Call struct {
+	// Channel is one of the sandbox.Channel constants.
+	Channel string
+	// Method is one of the Method constants.
+	Method string
+	// Payload is the argument of the method: a bell.NotificationPayload,
+	// BellBroadcast, mailer.Mail, EmailWithFilePaths, oca.OCA, oca.OCABulk
+	// or whatsapp.Whatsapp. An interceptor may replace it with another value of
+	// the same type before calling next.
+	Payload interface{}
+}
+
+// Handler continues a send, returning the result of the wrapped client.
+// Bell sends have no result, and bulk OCA sends return an oca.BulkResult.
+type Handler func(ctx context.Context, call *Call) (interface{}, error)
+
+type Interceptor func(ctx context.Context, call *Call, next Handler) (interface{}, error)
+
+// Chain returns an interceptor running interceptors in order, the first
+// one being the outermost.
+func Chain(interceptors ...Interceptor) Interceptor {
+	return func(ctx context.Context, call *Call, next Handler) (interface{}, error) {
+		return handler(interceptors, next)(ctx, call)
+	}
+}
+
+func handler(interceptors []Interceptor, final Handler) Handler {
+	if len(interceptors) == 0 {
+		return final
+	}
+	next := handler(interceptors[1:], final)
+	return func(ctx context.Context, call *Call) (interface{}, error) {
+		return interceptors[0](ctx, call, next)
+	}
+}
+
+func payloadError(call *Call, want interface{}) error {
+	return fmt.Errorf("interceptor: %s payload is %T, not %T", call.Method, call.Payload, want)
+}
@@ -0,0 +1,46 @@
+package interceptor
+
+import (
+	"context"
+
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/mailer"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/sandbox"
+)
+
+// EmailWithFilePaths is the payload of SendEmailWithFilePaths.
+type EmailWithFilePaths struct {
+	Mail      mailer.MailWithoutAttachments
+	FilePaths []string
+}
+
+type interceptedMailer struct {
+	client mailer.SmtpClient
+	chain  Interceptor
+}
+
+// NewMailer wraps client so every mail goes through interceptors.
+func NewMailer(client mailer.SmtpClient, interceptors ...Interceptor) mailer.SmtpClient {
+	return &interceptedMailer{client: client, chain: Chain(interceptors...)}
+}
+
+func (m *interceptedMailer) SendEmailWithFilePaths(ctx context.Context, mail mailer.MailWithoutAttachments, filePaths []string) (interface{}, error) {
+	call := &Call{Channel: sandbox.ChannelEmail, Method: MethodSendEmailWithFilePaths, Payload: EmailWithFilePaths{Mail: mail, FilePaths: filePaths}}
+	return m.chain(ctx, call, func(ctx context.Context, call *Call) (interface{}, error) {
+		payload, ok := call.Payload.(EmailWithFilePaths)
+		if !ok {
+			return nil, payloadError(call, payload)
+		}
+		return m.client.SendEmailWithFilePaths(ctx, payload.Mail, payload.FilePaths)
+	})
+}
+
+func (m *interceptedMailer) SendEmail(ctx context.Context, mail mailer.Mail) (interface{}, error) {
+	call := &Call{Channel: sandbox.ChannelEmail, Method: MethodSendEmail, Payload: mail}
+	return m.chain(ctx, call, func(ctx context.Context, call *Call) (interface{}, error) {
+		mail, ok := call.Payload.(mailer.Mail)
+		if !ok {
+			return nil, payloadError(call, mail)
+		}
+		return m.client.SendEmail(ctx, mail)
+	})
+}
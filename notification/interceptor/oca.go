@@ -0,0 +1,42 @@
+package interceptor
+
+import (
+	"context"
+
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/oca"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/sandbox"
+)
+
+type interceptedOCA struct {
+	client oca.OCAClient
+	chain  Interceptor
+}
+
+// NewOCA wraps client so every message goes through interceptors.
+func NewOCA(client oca.OCAClient, interceptors ...Interceptor) oca.OCAClient {
+	return &interceptedOCA{client: client, chain: Chain(interceptors...)}
+}
+
+func (o *interceptedOCA) SendWhatsapp(ctx context.Context, body oca.OCA) (interface{}, error) {
+	call := &Call{Channel: sandbox.ChannelOCA, Method: MethodSendWhatsapp, Payload: body}
+	return o.chain(ctx, call, func(ctx context.Context, call *Call) (interface{}, error) {
+		body, ok := call.Payload.(oca.OCA)
+		if !ok {
+			return nil, payloadError(call, body)
+		}
+		return o.client.SendWhatsapp(ctx, body)
+	})
+}
+
+func (o *interceptedOCA) SendWhatsappBulk(ctx context.Context, body oca.OCABulk) (oca.BulkResult, error) {
+	call := &Call{Channel: sandbox.ChannelOCA, Method: MethodSendWhatsappBulk, Payload: body}
+	data, err := o.chain(ctx, call, func(ctx context.Context, call *Call) (interface{}, error) {
+		body, ok := call.Payload.(oca.OCABulk)
+		if !ok {
+			return oca.BulkResult{}, payloadError(call, body)
+		}
+		return o.client.SendWhatsappBulk(ctx, body)
+	})
+	result, _ := data.(oca.BulkResult)
+	return result, err
+}
@@ -0,0 +1,49 @@
+package interceptor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/httpstatus"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/metrics"
+)
+
+// Retry returns an interceptor making up to attempts sends while they fail
+// with a transient error: a timeout, a network error, or a 429 or 5xx
+// response. It waits backoff times the number of the attempt between two
+// sends and records each retry with m.Retried, the method of the call
+// being the endpoint. A nil m discards them.
+func Retry(attempts int, backoff time.Duration, m metrics.Metrics) Interceptor {
+	if m == nil {
+		m = metrics.Nop{}
+	}
+	return func(ctx context.Context, call *Call, next Handler) (interface{}, error) {
+		data, err := next(ctx, call)
+		for attempt := 1; attempt < attempts && retryable(err); attempt++ {
+			timer := time.NewTimer(time.Duration(attempt) * backoff)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return data, err
+			case <-timer.C:
+			}
+			m.Retried(call.Channel, call.Method)
+			data, err = next(ctx, call)
+		}
+		return data, err
+	}
+}
+
+func retryable(err error) bool {
+	var statusErr *httpstatus.Error
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= 500
+	}
+	switch metrics.Reason(err) {
+	case metrics.ReasonTimeout, metrics.ReasonNetwork:
+		return !errors.Is(err, context.DeadlineExceeded)
+	}
+	return false
+}
@@ -0,0 +1,29 @@
+package interceptor
+
+import (
+	"context"
+
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/sandbox"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/whatsapp"
+)
+
+type interceptedWhatsapp struct {
+	client whatsapp.WhatsappClient
+	chain  Interceptor
+}
+
+// NewWhatsapp wraps client so every message goes through interceptors.
+func NewWhatsapp(client whatsapp.WhatsappClient, interceptors ...Interceptor) whatsapp.WhatsappClient {
+	return &interceptedWhatsapp{client: client, chain: Chain(interceptors...)}
+}
+
+func (w *interceptedWhatsapp) SendWhatsapp(ctx context.Context, body whatsapp.Whatsapp) (interface{}, error) {
+	call := &Call{Channel: sandbox.ChannelWhatsapp, Method: MethodSendWhatsapp, Payload: body}
+	return w.chain(ctx, call, func(ctx context.Context, call *Call) (interface{}, error) {
+		body, ok := call.Payload.(whatsapp.Whatsapp)
+		if !ok {
+			return nil, payloadError(call, body)
+		}
+		return w.client.SendWhatsapp(ctx, body)
+	})
+}
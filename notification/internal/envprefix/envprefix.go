@@ -0,0 +1,34 @@
+// Package envprefix passes the environment prefix of a policy to the
+// gateways through the context, so that texts rendered from templates
+// after the policy ran are prefixed too.
+package envprefix
+
+import (
+	"context"
+	"strings"
+)
+
+type key struct{}
+
+// With returns a copy of ctx carrying prefix.
+func With(ctx context.Context, prefix string) context.Context {
+	if prefix == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, key{}, prefix)
+}
+
+// Apply prepends the prefix carried by ctx to text.
+func Apply(ctx context.Context, text string) string {
+	prefix, _ := ctx.Value(key{}).(string)
+	return Add(prefix, text)
+}
+
+// Add prepends prefix to text unless either is empty or text already
+// starts with it.
+func Add(prefix, text string) string {
+	if prefix == "" || text == "" || strings.HasPrefix(text, prefix) {
+		return text
+	}
+	return prefix + " " + text
+}
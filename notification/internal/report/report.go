@@ -0,0 +1,43 @@
+// Package report records the outcome of the sends of the gateways.
+package report
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/metrics"
+)
+
+// Send logs the outcome of a send of channel with its recipient count and
+// latency, plus attrs, and records it in m.
+func Send(ctx context.Context, logger *slog.Logger, m metrics.Metrics, channel, endpoint string, recipients int, start time.Time, err error, attrs ...any) {
+	latency := time.Since(start)
+	attrs = append([]any{"channel", channel, "endpoint", endpoint, "recipients", recipients, "latency", latency}, attrs...)
+	if err != nil {
+		m.Failed(channel, endpoint, metrics.Reason(err), latency)
+		logger.ErrorContext(ctx, channel+" send failed", append(attrs, "error", err)...)
+		return
+	}
+	m.Sent(channel, endpoint, recipients, latency)
+	logger.InfoContext(ctx, channel+" sent", attrs...)
+}
+
+// Bulk logs and records a send of channel with one error per recipient:
+// every failed recipient as a failure and the others as one send.
+func Bulk(ctx context.Context, logger *slog.Logger, m metrics.Metrics, channel, endpoint string, start time.Time, errs []error) {
+	latency := time.Since(start)
+	sent := 0
+	for _, err := range errs {
+		if err == nil {
+			sent++
+			continue
+		}
+		m.Failed(channel, endpoint, metrics.Reason(err), latency)
+		logger.ErrorContext(ctx, channel+" send failed", "channel", channel, "endpoint", endpoint, "recipients", 1, "latency", latency, "error", err)
+	}
+	if sent > 0 {
+		m.Sent(channel, endpoint, sent, latency)
+		logger.InfoContext(ctx, channel+" sent", "channel", channel, "endpoint", endpoint, "recipients", sent, "latency", latency)
+	}
+}
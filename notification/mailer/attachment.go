@@ -0,0 +1,57 @@
+package mailer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+var ErrAttachmentTooLarge = errors.New("attachment exceeds maximum size")
+
+// open returns a stream over the attachment content, enforcing MaxSize.
+func (a Attachment) open() (io.ReadCloser, error) {
+	var rc io.ReadCloser
+	switch {
+	case a.Open != nil:
+		r, err := a.Open()
+		if err != nil {
+			return nil, fmt.Errorf("open attachment %s: %w", a.FileName, err)
+		}
+		rc = r
+	case a.Reader != nil:
+		rc = io.NopCloser(a.Reader)
+	case a.Content != nil:
+		rc = io.NopCloser(bytes.NewReader(a.Content))
+	case a.Path != "":
+		file, err := os.Open(a.Path)
+		if err != nil {
+			return nil, err
+		}
+		rc = file
+	default:
+		return nil, fmt.Errorf("attachment %s has no content", a.FileName)
+	}
+	if a.MaxSize > 0 {
+		rc = &limitedReadCloser{ReadCloser: rc, name: a.FileName, remaining: a.MaxSize}
+	}
+	return rc, nil
+}
+
+// limitedReadCloser fails with ErrAttachmentTooLarge instead of truncating
+// once more than the allowed number of bytes has been read.
+type limitedReadCloser struct {
+	io.ReadCloser
+	name      string
+	remaining int64
+}
+
+func (l *limitedReadCloser) Read(p []byte) (int, error) {
+	n, err := l.ReadCloser.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n, fmt.Errorf("%w: %s", ErrAttachmentTooLarge, l.name)
+	}
+	return n, err
+}
@@ -0,0 +1,281 @@
+// Package dkim signs outgoing messages with DKIM (RFC 6376) using RSA or
+// Ed25519 (RFC 8463) keys and relaxed/relaxed canonicalization.
+package dkim
+
+import (
+	"bufio"
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultHeaders are the header fields signed when none are configured.
+var DefaultHeaders = []string{
+	"From", "To", "Cc", "Subject", "Date", "Message-ID", "MIME-Version", "Content-Type",
+}
+
+type Signer struct {
+	domain    string
+	selector  string
+	key       crypto.Signer
+	algorithm string
+	headers   []string
+}
+
+// NewSigner creates a signer for domain and selector. headers lists the
+// header fields to sign; DefaultHeaders is used when it is empty.
+func NewSigner(domain, selector string, key crypto.Signer, headers ...string) (*Signer, error) {
+	if domain == "" || selector == "" {
+		return nil, errors.New("dkim domain and selector are required")
+	}
+	s := &Signer{domain: domain, selector: selector, key: key}
+	switch key.(type) {
+	case *rsa.PrivateKey:
+		s.algorithm = "rsa-sha256"
+	case ed25519.PrivateKey:
+		s.algorithm = "ed25519-sha256"
+	default:
+		return nil, fmt.Errorf("unsupported dkim key type %T", key)
+	}
+	if len(headers) == 0 {
+		headers = DefaultHeaders
+	}
+	hasFrom := false
+	for _, h := range headers {
+		if strings.EqualFold(h, "From") {
+			hasFrom = true
+		}
+	}
+	if !hasFrom {
+		headers = append([]string{"From"}, headers...)
+	}
+	s.headers = headers
+	return s, nil
+}
+
+// ParsePrivateKey parses a PEM encoded PKCS#1 RSA or PKCS#8 RSA/Ed25519
+// private key.
+func ParsePrivateKey(pemBytes []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, errors.New("dkim private key is not PEM encoded")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported dkim key type %T", key)
+	}
+	return signer, nil
+}
+
+// LoadPrivateKey reads a PEM encoded private key from a file.
+func LoadPrivateKey(path string) (crypto.Signer, error) {
+	pemBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePrivateKey(pemBytes)
+}
+
+// Signature reads a complete RFC 5322 message and returns the
+// DKIM-Signature header field, terminated by CRLF, to prepend to it. The body
+// is hashed as it is read so it is never held in memory.
+func (s *Signer) Signature(message io.Reader) (string, error) {
+	r := bufio.NewReader(message)
+	fields, err := readHeader(r)
+	if err != nil {
+		return "", err
+	}
+
+	bodyHash := sha256.New()
+	body := &relaxedBody{w: bodyHash}
+	if _, err := io.Copy(body, r); err != nil {
+		return "", err
+	}
+	body.finish()
+
+	signed := s.selectHeaders(fields)
+	names := make([]string, len(signed))
+	for i, f := range signed {
+		names[i] = f.name
+	}
+
+	tags := []string{
+		"v=1",
+		"a=" + s.algorithm,
+		"c=relaxed/relaxed",
+		"d=" + s.domain,
+		"s=" + s.selector,
+		"t=" + strconv.FormatInt(time.Now().Unix(), 10),
+		"h=" + strings.Join(names, ":"),
+		"bh=" + base64.StdEncoding.EncodeToString(bodyHash.Sum(nil)),
+		"b=",
+	}
+	value := strings.Join(tags, ";\r\n\t")
+
+	headerHash := sha256.New()
+	for _, f := range signed {
+		io.WriteString(headerHash, relaxedHeader(f.name, f.value)+"\r\n")
+	}
+	io.WriteString(headerHash, relaxedHeader("DKIM-Signature", value))
+
+	var sig []byte
+	switch s.algorithm {
+	case "rsa-sha256":
+		sig, err = s.key.Sign(rand.Reader, headerHash.Sum(nil), crypto.SHA256)
+	default:
+		sig, err = s.key.Sign(rand.Reader, headerHash.Sum(nil), crypto.Hash(0))
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return "DKIM-Signature: " + value + fold(base64.StdEncoding.EncodeToString(sig)) + "\r\n", nil
+}
+
+// fold splits the signature into continuation lines.
+func fold(b string) string {
+	const width = 72
+	var sb strings.Builder
+	for len(b) > width {
+		sb.WriteString(b[:width])
+		sb.WriteString("\r\n\t")
+		b = b[width:]
+	}
+	sb.WriteString(b)
+	return sb.String()
+}
+
+type headerField struct {
+	name  string
+	value string
+}
+
+func readHeader(r *bufio.Reader) ([]headerField, error) {
+	var fields []headerField
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		trimmed := strings.TrimRight(line, "\r\n")
+		if trimmed == "" {
+			return fields, nil
+		}
+		if (trimmed[0] == ' ' || trimmed[0] == '\t') && len(fields) > 0 {
+			fields[len(fields)-1].value += "\r\n" + trimmed
+			continue
+		}
+		name, value, ok := strings.Cut(trimmed, ":")
+		if !ok {
+			return nil, fmt.Errorf("malformed header line %q", trimmed)
+		}
+		fields = append(fields, headerField{name: name, value: value})
+		if err == io.EOF {
+			return fields, nil
+		}
+	}
+}
+
+// selectHeaders picks the configured header fields present in the message.
+// Repeated fields are signed from the bottom up as RFC 6376 5.4.2 requires.
+func (s *Signer) selectHeaders(fields []headerField) []headerField {
+	used := make(map[int]bool)
+	var signed []headerField
+	for _, name := range s.headers {
+		for i := len(fields) - 1; i >= 0; i-- {
+			if !used[i] && strings.EqualFold(strings.TrimSpace(fields[i].name), name) {
+				used[i] = true
+				signed = append(signed, fields[i])
+				break
+			}
+		}
+	}
+	return signed
+}
+
+// relaxedHeader applies the relaxed header canonicalization of RFC 6376
+// 3.4.2, without the trailing CRLF.
+func relaxedHeader(name, value string) string {
+	value = strings.NewReplacer("\r\n", "", "\n", "").Replace(value)
+	return strings.ToLower(strings.TrimSpace(name)) + ":" + strings.TrimSpace(collapseWSP(value))
+}
+
+func collapseWSP(s string) string {
+	var sb strings.Builder
+	space := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == ' ' || c == '\t' {
+			space = true
+			continue
+		}
+		if space {
+			sb.WriteByte(' ')
+			space = false
+		}
+		sb.WriteByte(c)
+	}
+	if space {
+		sb.WriteByte(' ')
+	}
+	return sb.String()
+}
+
+// relaxedBody applies the relaxed body canonicalization of RFC 6376 3.4.4
+// to a stream: whitespace runs become one space, trailing whitespace is
+// removed from lines and trailing empty lines are dropped.
+type relaxedBody struct {
+	w          io.Writer
+	line       bytes.Buffer
+	emptyLines int
+}
+
+func (b *relaxedBody) Write(p []byte) (int, error) {
+	for _, c := range p {
+		if c != '\n' {
+			b.line.WriteByte(c)
+			continue
+		}
+		b.flushLine()
+	}
+	return len(p), nil
+}
+
+func (b *relaxedBody) flushLine() {
+	line := strings.TrimRight(collapseWSP(strings.TrimSuffix(b.line.String(), "\r")), " ")
+	b.line.Reset()
+	if line == "" {
+		b.emptyLines++
+		return
+	}
+	for ; b.emptyLines > 0; b.emptyLines-- {
+		io.WriteString(b.w, "\r\n")
+	}
+	io.WriteString(b.w, line+"\r\n")
+}
+
+func (b *relaxedBody) finish() {
+	if b.line.Len() > 0 {
+		b.flushLine()
+	}
+}
@@ -0,0 +1,52 @@
+package mailer
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	htmlDropRegex    = regexp.MustCompile(`(?is)<(script|style|head|title)[^>]*>.*?</(script|style|head|title)\s*>`)
+	htmlCommentRegex = regexp.MustCompile(`(?s)<!--.*?-->`)
+	htmlLinkRegex    = regexp.MustCompile(`(?is)<a\s[^>]*href\s*=\s*["']([^"']*)["'][^>]*>(.*?)</a\s*>`)
+	htmlBreakRegex   = regexp.MustCompile(`(?i)<br\s*/?>`)
+	htmlBlockRegex   = regexp.MustCompile(`(?i)</?(p|div|h[1-6]|table|tr|ul|ol|blockquote|section|article|header|footer)(\s[^>]*)?>`)
+	htmlItemRegex    = regexp.MustCompile(`(?i)<li(\s[^>]*)?>`)
+	htmlCellRegex    = regexp.MustCompile(`(?i)</t[dh]\s*>`)
+	htmlTagRegex     = regexp.MustCompile(`(?s)<[^>]*>`)
+	spaceRegex       = regexp.MustCompile(`[ \t\r\f\v\x{00a0}]+`)
+	blankLinesRegex  = regexp.MustCompile(`\n{3,}`)
+)
+
+// htmlToText derives a readable plain text rendition of an HTML body for the
+// text/plain alternative part.
+func htmlToText(body string) string {
+	text := htmlDropRegex.ReplaceAllString(body, "")
+	text = htmlCommentRegex.ReplaceAllString(text, "")
+	text = htmlLinkRegex.ReplaceAllStringFunc(text, func(link string) string {
+		match := htmlLinkRegex.FindStringSubmatch(link)
+		label := strings.TrimSpace(htmlTagRegex.ReplaceAllString(match[2], ""))
+		href := strings.TrimSpace(match[1])
+		if href == "" || label == href || strings.HasPrefix(href, "#") {
+			return label
+		}
+		return label + " (" + href + ")"
+	})
+	text = strings.NewReplacer("\r\n", " ", "\n", " ").Replace(text)
+	text = htmlBreakRegex.ReplaceAllString(text, "\n")
+	text = htmlBlockRegex.ReplaceAllString(text, "\n\n")
+	text = htmlItemRegex.ReplaceAllString(text, "\n- ")
+	text = htmlCellRegex.ReplaceAllString(text, " ")
+	text = htmlTagRegex.ReplaceAllString(text, "")
+	text = html.UnescapeString(text)
+	text = spaceRegex.ReplaceAllString(text, " ")
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	text = strings.Join(lines, "\n")
+	text = blankLinesRegex.ReplaceAllString(text, "\n\n")
+	return strings.TrimSpace(text)
+}
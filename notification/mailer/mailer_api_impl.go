@@ -1,25 +1,36 @@
 package mailer
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"io"
 	"io/ioutil"
-	"log"
+	"log/slog"
+	"mime"
 	"mime/multipart"
 	"net/http"
-	"os"
+	"net/textproto"
 	"path/filepath"
 	"sync"
 	"time"
 
 	cfg "github.com/DamiaRalitsa/notif-lib-golang/notification/config"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/httpstatus"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/internal/report"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/metrics"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/option"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/sandbox"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/tracing"
 )
 
 type gatewayApi struct {
 	FabdBaseUrl string
 	ApiKey      string
+	HTTPClient  *http.Client
+	Sandbox     *sandbox.Sandbox
+	Logger      *slog.Logger
+	Metrics     metrics.Metrics
+	Tracer      *tracing.Tracer
 }
 
 type ApiResponse struct {
@@ -27,22 +38,31 @@ type ApiResponse struct {
 	Message string `json:"message"`
 }
 
-func NewMailerApiHandler() (SmtpClient, error) {
+func NewMailerApiHandler(opts ...option.Option) (SmtpClient, error) {
 	config, err := cfg.InitEnv(cfg.API)
 	if err != nil {
 		return nil, err
 	}
+	o, err := option.New(opts...)
+	if err != nil {
+		return nil, err
+	}
 	g := &gatewayApi{
-		FabdBaseUrl: config.ApiConfig.FabdBaseUrl,
+		FabdBaseUrl: o.BaseURLOr(config.ApiConfig.FabdBaseUrl),
 		ApiKey:      config.ApiConfig.ApiKey,
+		HTTPClient:  o.HTTPClient,
+		Sandbox:     o.Sandbox,
+		Logger:      o.Logger,
+		Metrics:     o.Metrics,
+		Tracer:      o.Tracer,
 	}
 	return g, err
 }
 
 func (g *gatewayApi) SendEmailWithFilePaths(ctx context.Context, mailWithoutAttachments MailWithoutAttachments, filePaths []string) (data interface{}, err error) {
-	start := time.Now()
+	ctx, span := g.Tracer.Start(ctx, "mailer.SendEmailWithFilePaths", sandbox.ChannelEmail, tracing.AttachmentsKey.Int(len(filePaths)))
 	defer func() {
-		log.Printf("readFiles %v", time.Since(start))
+		g.Tracer.End(span, err)
 	}()
 
 	attachments := make([]Attachment, len(filePaths))
@@ -86,62 +106,55 @@ func (g *gatewayApi) SendEmailWithFilePaths(ctx context.Context, mailWithoutAtta
 		Subject:      mailWithoutAttachments.Subject,
 		TemplateCode: mailWithoutAttachments.Message,
 		Data:         map[string]interface{}{"text": mailWithoutAttachments.Text},
+		Attachments:  attachments,
+		TextBody:     mailWithoutAttachments.Text,
 	}
 
 	return g.SendEmail(ctx, mail)
 }
 
 func (g *gatewayApi) SendEmail(ctx context.Context, payload Mail) (data interface{}, err error) {
+	ctx, span := g.Tracer.Start(ctx, "mailer.SendEmail", sandbox.ChannelEmail,
+		tracing.RecipientsKey.Int(len(recipients(payload))),
+		tracing.TemplateKey.String(payload.TemplateCode),
+	)
+	start := time.Now()
 	url := g.FabdBaseUrl + "/v4/webhooks/email-notifications"
-	form := &bytes.Buffer{}
-	writer := multipart.NewWriter(form)
-
-	for _, recipient := range payload.To {
-		_ = writer.WriteField("to", recipient)
-	}
-	for _, cc := range payload.CC {
-		_ = writer.WriteField("cc", cc)
-	}
-	for _, bcc := range payload.BCC {
-		_ = writer.WriteField("bcc", bcc)
-	}
-	_ = writer.WriteField("subject", payload.Subject)
-	_ = writer.WriteField("template_code", payload.TemplateCode)
-	dataJson, _ := json.Marshal(payload.Data)
-	_ = writer.WriteField("data", string(dataJson))
-
-	if len(payload.Attachments) > 0 {
-		for _, attachment := range payload.Attachments {
-			file, err := os.Open(attachment.Path)
-			if err != nil {
-				return nil, err
-			}
-			defer file.Close()
-
-			part, err := writer.CreateFormFile("attachments", attachment.FileName)
-			if err != nil {
-				return nil, err
-			}
-			_, err = io.Copy(part, file)
-			if err != nil {
-				return nil, err
-			}
+	defer func() {
+		g.Tracer.End(span, err)
+		report.Send(ctx, g.Logger, g.Metrics, sandbox.ChannelEmail, url, len(recipients(payload)), start, err)
+	}()
+	if g.Sandbox != nil {
+		if err := checkAttachments(payload); err != nil {
+			return nil, err
 		}
+		payload = sandboxMail(g.Sandbox, payload)
+		err = g.Sandbox.Capture(ctx, sandbox.Record{
+			Channel:    sandbox.ChannelEmail,
+			Endpoint:   url,
+			Recipients: recipients(payload),
+			Payload:    payload,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return ApiResponse{Status: true, Message: "Email notification sent (sandbox)"}, nil
 	}
+	form, writer := io.Pipe()
+	multipartWriter := multipart.NewWriter(writer)
+	go func() {
+		writer.CloseWithError(writeEmailForm(multipartWriter, payload))
+	}()
 
-	err = writer.Close()
-	if err != nil {
-		return nil, err
-	}
-
-	req, err := http.NewRequest(http.MethodPost, url, form)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, form)
 	if err != nil {
+		form.Close()
 		return nil, err
 	}
 	req.Header.Set("Authorization", g.ApiKey)
-	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Content-Type", multipartWriter.FormDataContentType())
 
-	client := &http.Client{}
+	client := g.HTTPClient
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, err
@@ -153,12 +166,77 @@ func (g *gatewayApi) SendEmail(ctx context.Context, payload Mail) (data interfac
 		return nil, err
 	}
 
+	if err := httpstatus.Check(resp, body); err != nil {
+		return nil, err
+	}
+
 	var apiResponse ApiResponse
 	err = json.Unmarshal(body, &apiResponse)
 	if err != nil {
 		return nil, err
 	}
 
-	log.Println("Response from external endpoint:", resp.Status)
+	g.Logger.DebugContext(ctx, "response from external endpoint", "channel", sandbox.ChannelEmail, "endpoint", url, "status", resp.StatusCode)
 	return apiResponse, nil
 }
+
+// writeEmailForm streams payload as the multipart form expected by FABD.
+// Attachments are copied straight from their source into the form.
+func writeEmailForm(writer *multipart.Writer, payload Mail) error {
+	for _, recipient := range payload.To {
+		_ = writer.WriteField("to", recipient)
+	}
+	for _, cc := range payload.CC {
+		_ = writer.WriteField("cc", cc)
+	}
+	for _, bcc := range payload.BCC {
+		_ = writer.WriteField("bcc", bcc)
+	}
+	_ = writer.WriteField("subject", payload.Subject)
+	_ = writer.WriteField("template_code", payload.TemplateCode)
+	dataJson, _ := json.Marshal(payload.Data)
+	_ = writer.WriteField("data", string(dataJson))
+	if payload.Locale != "" {
+		_ = writer.WriteField("locale", payload.Locale)
+	}
+
+	for _, attachment := range payload.Attachments {
+		if err := writeAttachmentFormFile(writer, attachment); err != nil {
+			return err
+		}
+	}
+
+	return writer.Close()
+}
+
+func writeAttachmentFormFile(writer *multipart.Writer, attachment Attachment) error {
+	file, err := attachment.open()
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	part, err := createAttachmentFormFile(writer, attachment)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(part, file)
+	return err
+}
+
+// createAttachmentFormFile adds a file field for attachment. Inline
+// attachments are sent as "inline_attachments" carrying their Content-ID so
+// FABD can embed them in the rendered template.
+func createAttachmentFormFile(writer *multipart.Writer, attachment Attachment) (io.Writer, error) {
+	if !attachment.Inline {
+		return writer.CreateFormFile("attachments", attachment.FileName)
+	}
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", mime.FormatMediaType("form-data", map[string]string{
+		"name":     "inline_attachments",
+		"filename": attachment.FileName,
+	}))
+	header.Set("Content-Type", "application/octet-stream")
+	header.Set("Content-ID", "<"+attachment.contentID()+">")
+	return writer.CreatePart(header)
+}
@@ -1,18 +1,31 @@
 package mailer
 
 import (
+	"bytes"
 	"context"
-	"encoding/base64"
+	"crypto"
+	"crypto/tls"
 	"fmt"
-	"io/ioutil"
-	"log"
+	"io"
+	"log/slog"
+	"net"
 	"net/smtp"
 	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+
 	cfg "github.com/DamiaRalitsa/notif-lib-golang/notification/config"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/internal/envprefix"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/internal/report"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/mailer/dkim"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/mailer/templates"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/metrics"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/option"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/sandbox"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/tracing"
 )
 
 type gateway struct {
@@ -21,26 +34,77 @@ type gateway struct {
 	Port     string
 	Username string
 	Password string
+	// Templates renders TemplateCode locally when set.
+	Templates *templates.Engine
+	// DKIM signs outgoing messages when set.
+	DKIM *dkim.Signer
+	// TLSConfig overrides the configuration used for STARTTLS.
+	TLSConfig *tls.Config
+	Sandbox   *sandbox.Sandbox
+	Logger    *slog.Logger
+	Metrics   metrics.Metrics
+	Tracer    *tracing.Tracer
 }
 
-func NewMailerHandler() (SmtpClient, error) {
+func NewMailerHandler(opts ...option.Option) (SmtpClient, error) {
 	config, err := cfg.InitEnv(cfg.EMAIL)
 	if err != nil {
 		return nil, err
 	}
+	o, err := option.New(opts...)
+	if err != nil {
+		return nil, err
+	}
+	if o.Templates == nil && config.EmailConfig.EmailTemplateDir != "" {
+		if o.Templates, err = templates.NewFromDir(config.EmailConfig.EmailTemplateDir); err != nil {
+			return nil, err
+		}
+	}
+	if o.DKIM == nil && config.EmailConfig.DKIM.Domain != "" {
+		if o.DKIM, err = newDKIMSigner(config.EmailConfig.DKIM); err != nil {
+			return nil, err
+		}
+	}
 	g := &gateway{
-		Host:     config.EmailConfig.EmailHost,
-		Port:     config.EmailConfig.EmailPort,
-		Username: config.EmailConfig.EmailUserName,
-		Password: config.EmailConfig.EmailPassword,
+		Host:      config.EmailConfig.EmailHost,
+		Port:      config.EmailConfig.EmailPort,
+		Username:  config.EmailConfig.EmailUserName,
+		Password:  config.EmailConfig.EmailPassword,
+		Templates: o.Templates,
+		DKIM:      o.DKIM,
+		TLSConfig: o.TLSConfig,
+		Sandbox:   o.Sandbox,
+		Logger:    o.Logger,
+		Metrics:   o.Metrics,
+		Tracer:    o.Tracer,
 	}
 	return g, err
 }
 
+func newDKIMSigner(config cfg.DKIMConfig) (*dkim.Signer, error) {
+	var key crypto.Signer
+	var err error
+	if config.PrivateKey != "" {
+		key, err = dkim.ParsePrivateKey([]byte(config.PrivateKey))
+	} else {
+		key, err = dkim.LoadPrivateKey(config.PrivateKeyFile)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load dkim private key: %v", err)
+	}
+	var headers []string
+	for _, header := range strings.Split(config.Headers, ",") {
+		if header = strings.TrimSpace(header); header != "" {
+			headers = append(headers, header)
+		}
+	}
+	return dkim.NewSigner(config.Domain, config.Selector, key, headers...)
+}
+
 func (g *gateway) SendEmailWithFilePaths(ctx context.Context, mailWithoutAttachments MailWithoutAttachments, filePaths []string) (data interface{}, err error) {
-	start := time.Now()
+	ctx, span := g.Tracer.Start(ctx, "mailer.SendEmailWithFilePaths", sandbox.ChannelEmail, tracing.AttachmentsKey.Int(len(filePaths)))
 	defer func() {
-		log.Printf("readFiles %v", time.Since(start))
+		g.Tracer.End(span, err)
 	}()
 
 	attachments := make([]Attachment, len(filePaths))
@@ -79,107 +143,178 @@ func (g *gateway) SendEmailWithFilePaths(ctx context.Context, mailWithoutAttachm
 		attachments[res.index] = res.attachment
 	}
 
+	// Message is the HTML body itself, which must not be rendered as a
+	// template name.
 	mail := Mail{
-		To:           mailWithoutAttachments.To,
-		Subject:      mailWithoutAttachments.Subject,
-		TemplateCode: mailWithoutAttachments.Message,
-		Data:         map[string]interface{}{"text": mailWithoutAttachments.Text},
+		To:          mailWithoutAttachments.To,
+		Subject:     mailWithoutAttachments.Subject,
+		Data:        map[string]interface{}{"text": mailWithoutAttachments.Text},
+		Attachments: attachments,
+		HTMLBody:    mailWithoutAttachments.Message,
+		TextBody:    mailWithoutAttachments.Text,
 	}
 
 	return g.SendEmail(ctx, mail)
 }
 
 func (g *gateway) SendEmail(ctx context.Context, mail Mail) (data interface{}, err error) {
+	ctx, span := g.Tracer.Start(ctx, "mailer.SendEmail", sandbox.ChannelEmail, tracing.RecipientsKey.Int(len(recipients(mail))))
 	start := time.Now()
+	defer func() {
+		g.Tracer.End(span, err)
+		report.Send(ctx, g.Logger, g.Metrics, sandbox.ChannelEmail, g.Host+":"+g.Port, len(recipients(mail)), start, err)
+	}()
 
 	from := g.Username
 	password := g.Password
 	smtpHost := g.Host
 	smtpPort := g.Port
 
-	headers := make(map[string]string)
-	headers["From"] = from
-	headers["To"] = strings.Join(mail.To, ",")
-	headers["Subject"] = mail.Subject
-	headers["MIME-Version"] = "1.0"
-	headers["Content-Type"] = `multipart/mixed; boundary="MULTIPART_BOUNDARY"`
-
-	header := ""
-	for k, v := range headers {
-		header += fmt.Sprintf("%s: %s\r\n", k, v)
+	mail, err = g.render(mail)
+	if err != nil {
+		return "Failed", err
+	}
+	mail.Subject = envprefix.Apply(ctx, mail.Subject)
+	if g.Sandbox != nil {
+		return g.captureEmail(ctx, from, mail)
 	}
 
-	bodyHeader := "--MULTIPART_BOUNDARY\r\n" +
-		`Content-Type: text/html; charset="UTF-8"` + "\r\n" +
-		"Content-Transfer-Encoding: 7bit\r\n" +
-		"\r\n" +
-		mail.TemplateCode +
-		"\r\n"
+	auth := smtp.PlainAuth("", from, password, smtpHost)
+	_, session := g.Tracer.StartClient(ctx, "smtp.session",
+		attribute.String("server.address", smtpHost),
+		attribute.String("server.port", smtpPort),
+	)
+	err = sendMail(smtpHost+":"+smtpPort, g.TLSConfig, auth, from, recipients(mail), func(w io.Writer) error {
+		if g.DKIM != nil {
+			return writeSignedMessage(w, g.DKIM, from, mail)
+		}
+		return writeMessage(w, from, mail)
+	})
+	g.Tracer.End(session, err)
 
-	type result struct {
-		encodedAttachment string
-		err               error
+	if err != nil {
+		return "Failed", err
 	}
+	return "OKAY", nil
+}
 
-	results := make(chan result, len(mail.Attachments))
-	var wg sync.WaitGroup
+// render fills the subject and bodies of mail from its template, mirroring
+// what FABD does for the API mailer. Explicit Subject, HTMLBody and TextBody
+// values take precedence over the rendered ones.
+func (g *gateway) render(mail Mail) (Mail, error) {
+	if g.Templates == nil || mail.HTMLBody != "" {
+		return mail, nil
+	}
+	rendered, err := g.Templates.Render(mail.TemplateCode, mail.Locale, mail.Data)
+	if err != nil {
+		return mail, err
+	}
+	if mail.Subject == "" {
+		mail.Subject = rendered.Subject
+	}
+	mail.HTMLBody = rendered.HTML
+	if mail.TextBody == "" {
+		mail.TextBody = rendered.Text
+	}
+	return mail, nil
+}
 
-	for _, attachment := range mail.Attachments {
-		wg.Add(1)
-		go func(attachment Attachment) {
-			defer wg.Done()
-			fileContent, err := ioutil.ReadFile(attachment.Path)
-			if err != nil {
-				results <- result{"", err}
-				return
-			}
-			encodedAttachment := "--MULTIPART_BOUNDARY\r\n" +
-				`Content-Type: application/octet-stream` + "\r\n" +
-				`Content-Transfer-Encoding: base64` + "\r\n" +
-				`Content-Disposition: attachment; filename="` + attachment.FileName + `"` + "\r\n" +
-				"\r\n" +
-				base64.StdEncoding.EncodeToString(fileContent) +
-				"\r\n"
-			results <- result{encodedAttachment, nil}
-		}(attachment)
+// captureEmail renders mail as it would be sent and hands it to the sandbox.
+func (g *gateway) captureEmail(ctx context.Context, from string, mail Mail) (data interface{}, err error) {
+	mail = sandboxMail(g.Sandbox, mail)
+	var message bytes.Buffer
+	if err := writeMessage(&message, from, mail); err != nil {
+		return "Failed", err
+	}
+	err = g.Sandbox.Capture(ctx, sandbox.Record{
+		Channel:    sandbox.ChannelEmail,
+		Endpoint:   g.Host + ":" + g.Port,
+		Recipients: recipients(mail),
+		Payload:    message.Bytes(),
+	})
+	if err != nil {
+		return "Failed", err
 	}
+	return "OKAY", nil
+}
 
-	go func() {
-		wg.Wait()
-		close(results)
-	}()
+// sendMail behaves like smtp.SendMail but streams the message produced by
+// write into the DATA command instead of requiring it in memory.
+func sendMail(addr string, tlsConfig *tls.Config, auth smtp.Auth, from string, to []string, write func(io.Writer) error) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	c, err := smtp.Dial(addr)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
 
-	newAttachments := ""
-	for res := range results {
-		if res.err != nil {
-			return "Failed", res.err
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{}
+		}
+		tlsConfig = tlsConfig.Clone()
+		if tlsConfig.ServerName == "" {
+			tlsConfig.ServerName = host
+		}
+		if err = c.StartTLS(tlsConfig); err != nil {
+			return err
 		}
-		newAttachments += res.encodedAttachment
 	}
-
-	wg.Wait()
-
-	newMessage := []byte(header + "\r\n" + bodyHeader + newAttachments + "--MULTIPART_BOUNDARY--")
-
-	auth := smtp.PlainAuth("", from, password, smtpHost)
-	err = smtp.SendMail(smtpHost+":"+smtpPort, auth, from, mail.To, newMessage)
-
+	if ok, _ := c.Extension("AUTH"); ok && auth != nil {
+		if err = c.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err = c.Mail(from); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err = c.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
 	if err != nil {
-		fmt.Println(err)
-		return "Failed", err
+		return err
+	}
+	// On failure the connection is dropped without terminating DATA, so a
+	// partially written message is never delivered.
+	if err = write(w); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
 	}
+	return c.Quit()
+}
 
-	log.Printf("sendNotif took %v", time.Since(start))
-	log.Println("Email Sent Successfully!")
-	return "OKAY", nil
+// recipients returns the envelope recipients of mail, including CC and BCC.
+func recipients(mail Mail) []string {
+	rcpt := make([]string, 0, len(mail.To)+len(mail.CC)+len(mail.BCC))
+	for _, list := range [][]string{mail.To, mail.CC, mail.BCC} {
+		for _, address := range list {
+			rcpt = append(rcpt, addrSpec(address))
+		}
+	}
+	return rcpt
 }
 
 func (g *gateway) NewSmtpClient() SmtpClient {
 	return &gateway{
-		BaseURL:  g.BaseURL,
-		Host:     g.Host,
-		Port:     g.Port,
-		Username: g.Username,
-		Password: g.Password,
+		BaseURL:   g.BaseURL,
+		Host:      g.Host,
+		Port:      g.Port,
+		Username:  g.Username,
+		Password:  g.Password,
+		Templates: g.Templates,
+		DKIM:      g.DKIM,
+		TLSConfig: g.TLSConfig,
+		Sandbox:   g.Sandbox,
+		Logger:    g.Logger,
+		Metrics:   g.Metrics,
+		Tracer:    g.Tracer,
 	}
 }
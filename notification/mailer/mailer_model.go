@@ -1,5 +1,7 @@
 package mailer
 
+import "io"
+
 type Mail struct {
 	To           []string               `json:"to" validate:"required"`
 	CC           []string               `json:"cc"`
@@ -8,10 +10,33 @@ type Mail struct {
 	TemplateCode string                 `json:"template_code" validate:"required"`
 	Data         map[string]interface{} `json:"data" validate:"required"`
 	Attachments  []Attachment           `json:"attachments"`
+	// HTMLBody is the HTML body sent over SMTP. When empty, TemplateCode is
+	// used as the HTML body.
+	HTMLBody string `json:"html_body,omitempty"`
+	// TextBody is the plain text alternative sent over SMTP. When empty, it is
+	// generated from the HTML body.
+	TextBody string `json:"text_body,omitempty"`
+	// Locale selects a locale variant of TemplateCode when it is rendered
+	// locally, e.g. "id-ID".
+	Locale string `json:"locale,omitempty"`
 }
 type Attachment struct {
 	FileName string `json:"file_name"`
 	Path     string `json:"path"`
+	// Inline embeds the attachment in the HTML body instead of listing it as
+	// a download. The HTML references it as "cid:" followed by its ContentID.
+	Inline bool `json:"inline,omitempty"`
+	// ContentID identifies an inline attachment. When empty, FileName is used.
+	ContentID string `json:"content_id,omitempty"`
+	// Content, Reader and Open source the attachment from memory, a stream
+	// or a lazy opener instead of Path. The first one set wins, in the order
+	// Open, Reader, Content, Path.
+	Content []byte                        `json:"-"`
+	Reader  io.Reader                     `json:"-"`
+	Open    func() (io.ReadCloser, error) `json:"-"`
+	// MaxSize rejects the attachment with ErrAttachmentTooLarge once more
+	// than MaxSize bytes are read from it. Zero means no limit.
+	MaxSize int64 `json:"max_size,omitempty"`
 }
 
 type MailWithoutAttachments struct {
@@ -21,3 +46,36 @@ type MailWithoutAttachments struct {
 	Message string   `json:"message"`
 	Text    string   `json:"text,omitempty"`
 }
+
+func (m Mail) html() string {
+	if m.HTMLBody != "" {
+		return m.HTMLBody
+	}
+	return m.TemplateCode
+}
+
+func (m Mail) text() string {
+	if m.TextBody != "" {
+		return m.TextBody
+	}
+	return htmlToText(m.html())
+}
+
+func (a Attachment) contentID() string {
+	if a.ContentID != "" {
+		return a.ContentID
+	}
+	return a.FileName
+}
+
+// splitAttachments separates inline attachments from regular ones.
+func (m Mail) splitAttachments() (inline, attached []Attachment) {
+	for _, attachment := range m.Attachments {
+		if attachment.Inline {
+			inline = append(inline, attachment)
+		} else {
+			attached = append(attached, attachment)
+		}
+	}
+	return inline, attached
+}
@@ -0,0 +1,84 @@
+package mailertest
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// AssertCount fails the test unless exactly n messages were received.
+func (s *Server) AssertCount(t testing.TB, n int) {
+	t.Helper()
+	if got := len(s.Messages()); got != n {
+		t.Errorf("mailertest: got %d messages, want %d", got, n)
+	}
+}
+
+// RequireMessage returns the i-th received message, waiting briefly for it
+// to arrive, and stops the test if it does not.
+func (s *Server) RequireMessage(t testing.TB, i int) *Message {
+	t.Helper()
+	messages, err := s.WaitForMessages(i+1, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return messages[i]
+}
+
+// AssertSubject fails the test unless the decoded subject equals subject.
+func (m *Message) AssertSubject(t testing.TB, subject string) {
+	t.Helper()
+	if m.Subject != subject {
+		t.Errorf("mailertest: subject is %q, want %q", m.Subject, subject)
+	}
+}
+
+// AssertRecipient fails the test unless address was an envelope recipient.
+func (m *Message) AssertRecipient(t testing.TB, address string) {
+	t.Helper()
+	for _, rcpt := range m.EnvelopeTo {
+		if strings.EqualFold(rcpt, address) {
+			return
+		}
+	}
+	t.Errorf("mailertest: %s is not a recipient of %v", address, m.EnvelopeTo)
+}
+
+// AssertHeader fails the test unless the header key has value.
+func (m *Message) AssertHeader(t testing.TB, key, value string) {
+	t.Helper()
+	if got := m.Header.Get(key); got != value {
+		t.Errorf("mailertest: header %s is %q, want %q", key, got, value)
+	}
+}
+
+// AssertTextContains fails the test unless the text body contains substr.
+func (m *Message) AssertTextContains(t testing.TB, substr string) {
+	t.Helper()
+	if !strings.Contains(m.Text, substr) {
+		t.Errorf("mailertest: text body %q does not contain %q", m.Text, substr)
+	}
+}
+
+// AssertHTMLContains fails the test unless the HTML body contains substr.
+func (m *Message) AssertHTMLContains(t testing.TB, substr string) {
+	t.Helper()
+	if !strings.Contains(m.HTML, substr) {
+		t.Errorf("mailertest: html body %q does not contain %q", m.HTML, substr)
+	}
+}
+
+// AssertAttachment fails the test unless an attachment named fileName with
+// the given content was received, and returns it.
+func (m *Message) AssertAttachment(t testing.TB, fileName string, content []byte) Part {
+	t.Helper()
+	part, ok := m.Attachment(fileName)
+	if !ok {
+		t.Errorf("mailertest: no attachment named %q", fileName)
+		return part
+	}
+	if content != nil && string(part.Body) != string(content) {
+		t.Errorf("mailertest: attachment %q content differs: got %d bytes, want %d", fileName, len(part.Body), len(content))
+	}
+	return part
+}
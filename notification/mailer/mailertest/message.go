@@ -0,0 +1,163 @@
+package mailertest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+)
+
+// Message is a captured message parsed back into structured form.
+type Message struct {
+	// EnvelopeFrom and EnvelopeTo are the MAIL FROM and RCPT TO addresses,
+	// which include BCC recipients absent from the headers.
+	EnvelopeFrom string
+	EnvelopeTo   []string
+	// Username is the authenticated user, empty without AUTH.
+	Username string
+	// TLS reports whether the message was sent after STARTTLS.
+	TLS bool
+
+	Raw     []byte
+	Header  mail.Header
+	From    []*mail.Address
+	To      []*mail.Address
+	Cc      []*mail.Address
+	Subject string
+
+	// Parts lists the leaf MIME parts in document order.
+	Parts       []Part
+	Text        string
+	HTML        string
+	Attachments []Part
+}
+
+// Part is a decoded leaf MIME part.
+type Part struct {
+	Header      textproto.MIMEHeader
+	ContentType string
+	Params      map[string]string
+	// Disposition is "attachment" or "inline", empty when not set.
+	Disposition string
+	FileName    string
+	ContentID   string
+	Body        []byte
+}
+
+// Parse parses a raw RFC 5322 message, decoding headers and transfer
+// encodings of every MIME part.
+func Parse(raw []byte) (*Message, error) {
+	parsed, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	m := &Message{Raw: raw, Header: parsed.Header}
+
+	decoder := new(mime.WordDecoder)
+	if m.Subject, err = decoder.DecodeHeader(parsed.Header.Get("Subject")); err != nil {
+		m.Subject = parsed.Header.Get("Subject")
+	}
+	m.From, _ = parsed.Header.AddressList("From")
+	m.To, _ = parsed.Header.AddressList("To")
+	m.Cc, _ = parsed.Header.AddressList("Cc")
+
+	header := textproto.MIMEHeader(parsed.Header)
+	if err := m.walk(header, parsed.Body); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (m *Message) walk(header textproto.MIMEHeader, body io.Reader) error {
+	contentType := header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "text/plain"
+	}
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType, params = "application/octet-stream", map[string]string{}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := reader.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := m.walk(part.Header, part); err != nil {
+				return err
+			}
+		}
+	}
+
+	decoded, err := io.ReadAll(decode(header.Get("Content-Transfer-Encoding"), body))
+	if err != nil {
+		return err
+	}
+	part := Part{
+		Header:      header,
+		ContentType: mediaType,
+		Params:      params,
+		ContentID:   strings.Trim(header.Get("Content-ID"), "<>"),
+		Body:        decoded,
+	}
+	if disposition, dispParams, err := mime.ParseMediaType(header.Get("Content-Disposition")); err == nil {
+		part.Disposition = disposition
+		part.FileName = dispParams["filename"]
+	}
+	if part.FileName == "" {
+		part.FileName = params["name"]
+	}
+	m.Parts = append(m.Parts, part)
+
+	switch {
+	case part.Disposition != "" || part.FileName != "":
+		m.Attachments = append(m.Attachments, part)
+	case mediaType == "text/plain" && m.Text == "":
+		m.Text = string(decoded)
+	case mediaType == "text/html" && m.HTML == "":
+		m.HTML = string(decoded)
+	}
+	return nil
+}
+
+func decode(encoding string, r io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, r)
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	default:
+		return r
+	}
+}
+
+// Attachment returns the attachment or inline part named fileName.
+func (m *Message) Attachment(fileName string) (Part, bool) {
+	for _, part := range m.Attachments {
+		if part.FileName == fileName {
+			return part, true
+		}
+	}
+	return Part{}, false
+}
+
+// Recipients returns the bare addresses of the To and Cc headers.
+func (m *Message) Recipients() []string {
+	var recipients []string
+	for _, list := range [][]*mail.Address{m.To, m.Cc} {
+		for _, address := range list {
+			recipients = append(recipients, address.Address)
+		}
+	}
+	return recipients
+}
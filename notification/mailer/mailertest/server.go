@@ -0,0 +1,510 @@
+// Package mailertest provides an in-process SMTP server that captures the
+// messages sent by the mailer so email flows can be tested offline.
+//
+//	srv := mailertest.NewServer(mailertest.WithAuth("user@example.com", "secret"))
+//	defer srv.Close()
+//	srv.Setenv(t)
+//
+//	client, _ := mailer.NewMailerHandler()
+//	client.SendEmail(ctx, mail)
+//
+//	msg := srv.RequireMessage(t, 0)
+//	msg.AssertSubject(t, "Welcome")
+package mailertest
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/config"
+)
+
+type Server struct {
+	// Addr is the host:port the server listens on.
+	Addr string
+	Host string
+	Port string
+
+	username   string
+	password   string
+	startTLS   bool
+	requireTLS bool
+	tlsConfig  *tls.Config
+	clientTLS  *tls.Config
+
+	listener net.Listener
+	wg       sync.WaitGroup
+
+	mu       sync.Mutex
+	conns    map[net.Conn]struct{}
+	messages []*Message
+	notify   chan struct{}
+	closed   bool
+}
+
+type ServerOption func(*Server)
+
+// WithAuth requires clients to authenticate with AUTH PLAIN or AUTH LOGIN
+// using the given credentials.
+func WithAuth(username, password string) ServerOption {
+	return func(s *Server) {
+		s.username = username
+		s.password = password
+	}
+}
+
+// WithSTARTTLS advertises STARTTLS using a self-signed certificate. Use
+// ClientTLSConfig to trust it.
+func WithSTARTTLS() ServerOption {
+	return func(s *Server) {
+		s.startTLS = true
+	}
+}
+
+// RequireTLS rejects mail transactions that did not negotiate STARTTLS.
+func RequireTLS() ServerOption {
+	return func(s *Server) {
+		s.startTLS = true
+		s.requireTLS = true
+	}
+}
+
+// NewServer starts a server on a random loopback port. It panics if the
+// server cannot be started, like httptest.NewServer.
+func NewServer(opts ...ServerOption) *Server {
+	s := &Server{notify: make(chan struct{}), conns: make(map[net.Conn]struct{})}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.startTLS {
+		if err := s.generateCertificate(); err != nil {
+			panic(fmt.Sprintf("mailertest: failed to generate certificate: %v", err))
+		}
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(fmt.Sprintf("mailertest: failed to listen: %v", err))
+	}
+	s.listener = listener
+	s.Addr = listener.Addr().String()
+	s.Host, s.Port, _ = net.SplitHostPort(s.Addr)
+
+	s.wg.Add(1)
+	go s.serve()
+	return s
+}
+
+// Close stops the server and waits for open sessions to finish.
+func (s *Server) Close() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+	s.listener.Close()
+	s.wg.Wait()
+}
+
+// Env sets environment variables, like testing.TB does for the duration of
+// a test.
+type Env interface {
+	Setenv(key, value string)
+}
+
+// Setenv points the NOTIF_EMAIL_* variables at the server so
+// mailer.NewMailerHandler connects to it.
+func (s *Server) Setenv(env Env) {
+	username := s.username
+	if username == "" {
+		username = "mailertest@localhost"
+	}
+	password := s.password
+	if password == "" {
+		password = "mailertest"
+	}
+	env.Setenv(config.EmailHost, s.Host)
+	env.Setenv(config.EmailPort, s.Port)
+	env.Setenv(config.EmailUserName, username)
+	env.Setenv(config.EmailPassword, password)
+}
+
+// ClientTLSConfig returns a TLS configuration trusting the server
+// certificate, for use with option.WithTLSConfig.
+func (s *Server) ClientTLSConfig() *tls.Config {
+	if s.clientTLS == nil {
+		return nil
+	}
+	return s.clientTLS.Clone()
+}
+
+// Messages returns the messages received so far.
+func (s *Server) Messages() []*Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*Message(nil), s.messages...)
+}
+
+// Reset discards the received messages.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = nil
+}
+
+// WaitForMessages blocks until at least n messages were received or the
+// timeout expires, and returns the received messages.
+func (s *Server) WaitForMessages(n int, timeout time.Duration) ([]*Message, error) {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	for {
+		s.mu.Lock()
+		messages := append([]*Message(nil), s.messages...)
+		notify := s.notify
+		s.mu.Unlock()
+		if len(messages) >= n {
+			return messages, nil
+		}
+		select {
+		case <-notify:
+		case <-deadline.C:
+			return messages, fmt.Errorf("mailertest: received %d of %d messages within %v", len(messages), n, timeout)
+		}
+	}
+}
+
+func (s *Server) record(m *Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = append(s.messages, m)
+	close(s.notify)
+	s.notify = make(chan struct{})
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return
+		}
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+			s.mu.Lock()
+			delete(s.conns, conn)
+			s.mu.Unlock()
+		}()
+	}
+}
+
+// session is the state of one SMTP connection.
+type session struct {
+	server        *Server
+	conn          net.Conn
+	text          *textproto.Conn
+	tls           bool
+	authenticated string
+	from          string
+	to            []string
+}
+
+func (s *Server) handle(conn net.Conn) {
+	sess := &session{server: s, conn: conn, text: textproto.NewConn(conn)}
+	defer func() { sess.text.Close() }()
+
+	_ = conn.SetDeadline(time.Now().Add(time.Minute))
+	sess.reply(220, "mailertest ESMTP ready")
+	for {
+		line, err := sess.text.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			sess.ehlo()
+		case "HELO":
+			sess.reply(250, "mailertest")
+		case "STARTTLS":
+			if !sess.startTLS() {
+				return
+			}
+		case "AUTH":
+			sess.auth(arg)
+		case "MAIL":
+			sess.mail(arg)
+		case "RCPT":
+			sess.rcpt(arg)
+		case "DATA":
+			if !sess.data() {
+				return
+			}
+		case "RSET":
+			sess.from, sess.to = "", nil
+			sess.reply(250, "OK")
+		case "NOOP":
+			sess.reply(250, "OK")
+		case "QUIT":
+			sess.reply(221, "Bye")
+			return
+		default:
+			sess.reply(502, "Command not implemented")
+		}
+	}
+}
+
+func (sess *session) reply(code int, lines ...string) {
+	for i, line := range lines {
+		sep := " "
+		if i < len(lines)-1 {
+			sep = "-"
+		}
+		_ = sess.text.PrintfLine("%d%s%s", code, sep, line)
+	}
+}
+
+func (sess *session) ehlo() {
+	lines := []string{"mailertest", "8BITMIME", "PIPELINING"}
+	if sess.server.startTLS && !sess.tls {
+		lines = append(lines, "STARTTLS")
+	}
+	if sess.server.username != "" {
+		lines = append(lines, "AUTH PLAIN LOGIN")
+	}
+	sess.reply(250, lines...)
+}
+
+func (sess *session) startTLS() bool {
+	if !sess.server.startTLS || sess.tls {
+		sess.reply(502, "STARTTLS not available")
+		return true
+	}
+	sess.reply(220, "Ready to start TLS")
+	tlsConn := tls.Server(sess.conn, sess.server.tlsConfig)
+	if err := tlsConn.Handshake(); err != nil {
+		return false
+	}
+	sess.conn = tlsConn
+	sess.text = textproto.NewConn(tlsConn)
+	sess.tls = true
+	sess.authenticated, sess.from, sess.to = "", "", nil
+	return true
+}
+
+func (sess *session) auth(arg string) {
+	if sess.server.username == "" {
+		sess.reply(502, "AUTH not available")
+		return
+	}
+	mechanism, initial, _ := strings.Cut(arg, " ")
+	var username, password string
+	switch strings.ToUpper(mechanism) {
+	case "PLAIN":
+		if initial == "" {
+			sess.reply(334, "")
+			line, err := sess.text.ReadLine()
+			if err != nil {
+				return
+			}
+			initial = line
+		}
+		decoded, err := base64.StdEncoding.DecodeString(initial)
+		if err != nil {
+			sess.reply(501, "Malformed AUTH input")
+			return
+		}
+		fields := strings.Split(string(decoded), "\x00")
+		if len(fields) != 3 {
+			sess.reply(501, "Malformed AUTH input")
+			return
+		}
+		username, password = fields[1], fields[2]
+	case "LOGIN":
+		var err error
+		if username, err = sess.prompt("Username:", initial); err != nil {
+			sess.reply(501, "Malformed AUTH input")
+			return
+		}
+		if password, err = sess.prompt("Password:", ""); err != nil {
+			sess.reply(501, "Malformed AUTH input")
+			return
+		}
+	default:
+		sess.reply(504, "Unrecognized authentication type")
+		return
+	}
+	if username != sess.server.username || password != sess.server.password {
+		sess.reply(535, "Authentication credentials invalid")
+		return
+	}
+	sess.authenticated = username
+	sess.reply(235, "Authentication successful")
+}
+
+func (sess *session) prompt(challenge, initial string) (string, error) {
+	if initial == "" {
+		sess.reply(334, base64.StdEncoding.EncodeToString([]byte(challenge)))
+		line, err := sess.text.ReadLine()
+		if err != nil {
+			return "", err
+		}
+		initial = line
+	}
+	decoded, err := base64.StdEncoding.DecodeString(initial)
+	return string(decoded), err
+}
+
+func (sess *session) mail(arg string) {
+	if sess.server.requireTLS && !sess.tls {
+		sess.reply(530, "Must issue a STARTTLS command first")
+		return
+	}
+	if sess.server.username != "" && sess.authenticated == "" {
+		sess.reply(530, "Authentication required")
+		return
+	}
+	address, ok := pathArg(arg, "FROM:")
+	if !ok {
+		sess.reply(501, "Syntax: MAIL FROM:<address>")
+		return
+	}
+	sess.from, sess.to = address, nil
+	sess.reply(250, "OK")
+}
+
+func (sess *session) rcpt(arg string) {
+	if sess.from == "" {
+		sess.reply(503, "Need MAIL before RCPT")
+		return
+	}
+	address, ok := pathArg(arg, "TO:")
+	if !ok || address == "" {
+		sess.reply(501, "Syntax: RCPT TO:<address>")
+		return
+	}
+	sess.to = append(sess.to, address)
+	sess.reply(250, "OK")
+}
+
+func (sess *session) data() bool {
+	if sess.from == "" || len(sess.to) == 0 {
+		sess.reply(503, "Need MAIL and RCPT before DATA")
+		return true
+	}
+	sess.reply(354, "End data with <CR><LF>.<CR><LF>")
+	raw, err := readData(sess.text.Reader.R)
+	if err != nil {
+		return false
+	}
+
+	message, err := Parse(raw)
+	if err != nil {
+		sess.reply(554, "Malformed message: "+err.Error())
+		return true
+	}
+	message.EnvelopeFrom = sess.from
+	message.EnvelopeTo = sess.to
+	message.Username = sess.authenticated
+	message.TLS = sess.tls
+	sess.server.record(message)
+
+	sess.from, sess.to = "", nil
+	sess.reply(250, "OK: queued")
+	return true
+}
+
+// readData reads a DATA payload up to the terminating dot line, undoing dot
+// stuffing while preserving the original CRLF line endings.
+func readData(r *bufio.Reader) ([]byte, error) {
+	var raw []byte
+	for {
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			return nil, err
+		}
+		if string(line) == ".\r\n" || string(line) == ".\n" {
+			return raw, nil
+		}
+		if len(line) > 0 && line[0] == '.' {
+			line = line[1:]
+		}
+		raw = append(raw, line...)
+	}
+}
+
+func pathArg(arg, prefix string) (string, bool) {
+	if len(arg) < len(prefix) || !strings.EqualFold(arg[:len(prefix)], prefix) {
+		return "", false
+	}
+	path := strings.TrimSpace(arg[len(prefix):])
+	if i := strings.Index(path, " "); i >= 0 {
+		path = path[:i]
+	}
+	if !strings.HasPrefix(path, "<") || !strings.HasSuffix(path, ">") {
+		return "", false
+	}
+	return path[1 : len(path)-1], true
+}
+
+func (s *Server) generateCertificate() error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{Organization: []string{"mailertest"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return err
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	s.tlsConfig = &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key, Leaf: cert}},
+	}
+	s.clientTLS = &tls.Config{RootCAs: pool}
+	return nil
+}
@@ -0,0 +1,318 @@
+package mailer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/http"
+	"net/mail"
+	"net/textproto"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/mailer/dkim"
+)
+
+// base64LineLength is the maximum encoded line length allowed by RFC 2045.
+const base64LineLength = 76
+
+// maxSignedMessage bounds the size of a DKIM signed message, which is
+// held in memory while it is signed.
+const maxSignedMessage = 32 << 20
+
+// ErrMessageTooLarge is returned for a DKIM signed message larger than
+// 32 MiB.
+var ErrMessageTooLarge = errors.New("signed message exceeds maximum size")
+
+// writeSignedMessage writes m preceded by its DKIM signature. The message is
+// buffered in memory first because the signature covers the body but must
+// be sent ahead of it; nothing is written to disk.
+func writeSignedMessage(w io.Writer, signer *dkim.Signer, from string, m Mail) error {
+	var message bytes.Buffer
+	if err := writeMessage(&limitedWriter{w: &message, remaining: maxSignedMessage}, from, m); err != nil {
+		return err
+	}
+	signature, err := signer.Signature(bytes.NewReader(message.Bytes()))
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, signature); err != nil {
+		return err
+	}
+	_, err = message.WriteTo(w)
+	return err
+}
+
+// limitedWriter fails with ErrMessageTooLarge once more than remaining
+// bytes are written.
+type limitedWriter struct {
+	w         io.Writer
+	remaining int64
+}
+
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	if int64(len(p)) > l.remaining {
+		return 0, ErrMessageTooLarge
+	}
+	l.remaining -= int64(len(p))
+	return l.w.Write(p)
+}
+
+// writeMessage renders m as an RFC 5322 message with a MIME body.
+func writeMessage(w io.Writer, from string, m Mail) error {
+	mw := multipart.NewWriter(w)
+
+	h := &headerWriter{w: w}
+	h.address("From", from)
+	h.address("To", m.To...)
+	h.address("Cc", m.CC...)
+	h.set("Subject", mime.QEncoding.Encode("UTF-8", m.Subject))
+	h.set("Date", time.Now().Format(time.RFC1123Z))
+	h.set("Message-ID", messageID(from))
+	h.set("MIME-Version", "1.0")
+	h.set("Content-Type", mime.FormatMediaType("multipart/mixed", map[string]string{"boundary": mw.Boundary()}))
+	h.end()
+	if h.err != nil {
+		return h.err
+	}
+
+	inline, attached := m.splitAttachments()
+	if len(inline) > 0 {
+		if err := writeRelatedPart(mw, m.text(), m.html(), inline); err != nil {
+			return err
+		}
+	} else if err := writeAlternativePart(mw, m.text(), m.html()); err != nil {
+		return err
+	}
+	for _, attachment := range attached {
+		if err := writeAttachmentPart(mw, attachment); err != nil {
+			return err
+		}
+	}
+	return mw.Close()
+}
+
+// writeRelatedPart writes a multipart/related part whose root is the body
+// alternatives, followed by the inline attachments it references by CID.
+func writeRelatedPart(mw *multipart.Writer, text, html string, inline []Attachment) error {
+	related, err := createMultipart(mw, "multipart/related", map[string]string{"type": "multipart/alternative"})
+	if err != nil {
+		return err
+	}
+	if err := writeAlternativePart(related, text, html); err != nil {
+		return err
+	}
+	for _, attachment := range inline {
+		if err := writeAttachmentPart(related, attachment); err != nil {
+			return err
+		}
+	}
+	return related.Close()
+}
+
+// writeAlternativePart writes a multipart/alternative part holding the text
+// and HTML renditions of the body, least preferred first as RFC 2046 requires.
+func writeAlternativePart(mw *multipart.Writer, text, html string) error {
+	alternative, err := createMultipart(mw, "multipart/alternative", nil)
+	if err != nil {
+		return err
+	}
+	if err := writeTextPart(alternative, "text/plain", text); err != nil {
+		return err
+	}
+	if err := writeTextPart(alternative, "text/html", html); err != nil {
+		return err
+	}
+	return alternative.Close()
+}
+
+// createMultipart starts a nested multipart part of the given media type
+// inside mw and returns the writer for its children.
+func createMultipart(mw *multipart.Writer, mediaType string, params map[string]string) (*multipart.Writer, error) {
+	boundary := randomBoundary()
+	if params == nil {
+		params = map[string]string{}
+	}
+	params["boundary"] = boundary
+	part, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type": {mime.FormatMediaType(mediaType, params)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	nested := multipart.NewWriter(part)
+	if err := nested.SetBoundary(boundary); err != nil {
+		return nil, err
+	}
+	return nested, nil
+}
+
+func randomBoundary() string {
+	b := make([]byte, 24)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func writeTextPart(mw *multipart.Writer, mediaType, body string) error {
+	part, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {mime.FormatMediaType(mediaType, map[string]string{"charset": "UTF-8"})},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return err
+	}
+	qp := quotedprintable.NewWriter(part)
+	if _, err := io.WriteString(qp, body); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+func writeAttachmentPart(mw *multipart.Writer, attachment Attachment) error {
+	file, err := attachment.open()
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	sniff := make([]byte, 512)
+	n, err := io.ReadFull(file, sniff)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return err
+	}
+	sniff = sniff[:n]
+
+	disposition := "attachment"
+	header := textproto.MIMEHeader{
+		"Content-Type":              {attachmentContentType(attachment.FileName, sniff)},
+		"Content-Transfer-Encoding": {"base64"},
+	}
+	if attachment.Inline {
+		disposition = "inline"
+		header.Set("Content-ID", "<"+attachment.contentID()+">")
+	}
+	header.Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": attachment.FileName}))
+
+	part, err := mw.CreatePart(header)
+	if err != nil {
+		return err
+	}
+
+	encoder := base64.NewEncoder(base64.StdEncoding, &lineWrapper{w: part, max: base64LineLength})
+	if _, err := encoder.Write(sniff); err != nil {
+		return err
+	}
+	if _, err := io.Copy(encoder, file); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// attachmentContentType detects the media type of an attachment from its
+// file extension, falling back to sniffing its first bytes.
+func attachmentContentType(fileName string, head []byte) string {
+	contentType := mime.TypeByExtension(filepath.Ext(fileName))
+	if contentType == "" {
+		contentType = http.DetectContentType(head)
+	}
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType, params = "application/octet-stream", map[string]string{}
+	}
+	if fileName != "" {
+		params["name"] = fileName
+	}
+	return mime.FormatMediaType(mediaType, params)
+}
+
+func messageID(from string) string {
+	domain := "localhost"
+	if address := addrSpec(from); strings.Contains(address, "@") {
+		domain = address[strings.LastIndex(address, "@")+1:]
+	}
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return fmt.Sprintf("<%s@%s>", hex.EncodeToString(b), domain)
+}
+
+// addrSpec strips the display name from address, leaving the bare mailbox
+// suitable for the SMTP envelope.
+func addrSpec(address string) string {
+	if parsed, err := mail.ParseAddress(address); err == nil {
+		return parsed.Address
+	}
+	return address
+}
+
+type headerWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (h *headerWriter) set(key, value string) {
+	if h.err != nil {
+		return
+	}
+	_, h.err = fmt.Fprintf(h.w, "%s: %s\r\n", key, value)
+}
+
+// address writes an address list header, encoding display names per RFC 2047.
+func (h *headerWriter) address(key string, addresses ...string) {
+	if len(addresses) == 0 {
+		return
+	}
+	formatted := make([]string, 0, len(addresses))
+	for _, address := range addresses {
+		if parsed, err := mail.ParseAddress(address); err == nil {
+			address = parsed.String()
+		}
+		formatted = append(formatted, address)
+	}
+	h.set(key, strings.Join(formatted, ", "))
+}
+
+func (h *headerWriter) end() {
+	if h.err != nil {
+		return
+	}
+	_, h.err = io.WriteString(h.w, "\r\n")
+}
+
+// lineWrapper inserts CRLF after every max bytes written through it.
+type lineWrapper struct {
+	w   io.Writer
+	max int
+	n   int
+}
+
+func (l *lineWrapper) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		if l.n == l.max {
+			if _, err := io.WriteString(l.w, "\r\n"); err != nil {
+				return written, err
+			}
+			l.n = 0
+		}
+		chunk := l.max - l.n
+		if chunk > len(p) {
+			chunk = len(p)
+		}
+		n, err := l.w.Write(p[:chunk])
+		written += n
+		l.n += n
+		if err != nil {
+			return written, err
+		}
+		p = p[chunk:]
+	}
+	return written, nil
+}
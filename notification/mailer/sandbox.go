@@ -0,0 +1,33 @@
+package mailer
+
+import (
+	"io"
+
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/sandbox"
+)
+
+// sandboxMail redirects every recipient of mail to the sandbox catch-all
+// address, when one is configured.
+func sandboxMail(s *sandbox.Sandbox, mail Mail) Mail {
+	if s.CatchAllEmail != "" {
+		mail.To, mail.CC, mail.BCC = []string{s.CatchAllEmail}, nil, nil
+	}
+	return mail
+}
+
+// checkAttachments reads every attachment of mail as a send would, so that
+// missing files and attachments over MaxSize fail in sandbox mode too.
+func checkAttachments(mail Mail) error {
+	for _, attachment := range mail.Attachments {
+		file, err := attachment.open()
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(io.Discard, file)
+		file.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+// Package templates renders email templates locally so the SMTP mailer
+// produces the same subject and bodies FABD renders for the API mailer.
+//
+// A template source is an fs.FS laid out as:
+//
+//	layouts/*.html, layouts/*.txt    shared layouts
+//	partials/*.html, partials/*.txt  shared partials
+//	<code>/subject.txt               subject (text/template)
+//	<code>/body.html                 HTML body (html/template)
+//	<code>/body.txt                  optional text body (text/template)
+//	<code>/<locale>/...              per-locale overrides of the files above
+//
+// Layouts and partials are parsed together with each body, so a body selects
+// its layout by invoking it, e.g. {{template "base" .}} with the layout
+// calling {{template "content" .}} defined by the body.
+package templates
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+)
+
+const (
+	subjectFile = "subject.txt"
+	htmlFile    = "body.html"
+	textFile    = "body.txt"
+)
+
+var ErrTemplateNotFound = errors.New("email template not found")
+
+// Rendered is the output of rendering a template.
+type Rendered struct {
+	Subject string
+	HTML    string
+	Text    string
+}
+
+type Engine struct {
+	fsys  fs.FS
+	funcs map[string]interface{}
+	cache sync.Map
+}
+
+type Option func(*Engine)
+
+// WithFuncs makes funcs available to every template.
+func WithFuncs(funcs map[string]interface{}) Option {
+	return func(e *Engine) {
+		for name, fn := range funcs {
+			e.funcs[name] = fn
+		}
+	}
+}
+
+// New creates an engine reading templates from fsys, such as an embed.FS.
+func New(fsys fs.FS, opts ...Option) *Engine {
+	e := &Engine{fsys: fsys, funcs: map[string]interface{}{}}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// NewFromDir creates an engine reading templates from a directory.
+func NewFromDir(dir string, opts ...Option) (*Engine, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("template path %s is not a directory", dir)
+	}
+	return New(os.DirFS(dir), opts...), nil
+}
+
+// Render renders the template identified by code with data. Locale files
+// are preferred in the order "en-US", "en", then the default files.
+func (e *Engine) Render(code, locale string, data map[string]interface{}) (Rendered, error) {
+	set, err := e.load(code, locale)
+	if err != nil {
+		return Rendered{}, err
+	}
+
+	var rendered Rendered
+	var buf bytes.Buffer
+	if set.subject != nil {
+		if err := set.subject.Execute(&buf, data); err != nil {
+			return Rendered{}, fmt.Errorf("render %s subject: %w", code, err)
+		}
+		rendered.Subject = strings.TrimSpace(buf.String())
+		buf.Reset()
+	}
+	if err := set.html.Execute(&buf, data); err != nil {
+		return Rendered{}, fmt.Errorf("render %s html body: %w", code, err)
+	}
+	rendered.HTML = buf.String()
+	buf.Reset()
+	if set.text != nil {
+		if err := set.text.Execute(&buf, data); err != nil {
+			return Rendered{}, fmt.Errorf("render %s text body: %w", code, err)
+		}
+		rendered.Text = buf.String()
+	}
+	return rendered, nil
+}
+
+type templateSet struct {
+	subject *texttemplate.Template
+	html    *htmltemplate.Template
+	text    *texttemplate.Template
+}
+
+func (e *Engine) load(code, locale string) (*templateSet, error) {
+	if code == "" || strings.Contains(code, "..") || !fs.ValidPath(code) {
+		return nil, fmt.Errorf("%w: %q", ErrTemplateNotFound, code)
+	}
+	key := code + "\x00" + locale
+	if set, ok := e.cache.Load(key); ok {
+		return set.(*templateSet), nil
+	}
+
+	dirs := localeDirs(code, locale)
+	htmlPath := e.find(dirs, htmlFile)
+	if htmlPath == "" {
+		return nil, fmt.Errorf("%w: %s", ErrTemplateNotFound, code)
+	}
+
+	set := &templateSet{}
+	var err error
+	if set.html, err = e.parseHTML(htmlPath); err != nil {
+		return nil, err
+	}
+	if p := e.find(dirs, subjectFile); p != "" {
+		if set.subject, err = e.parseText(p, false); err != nil {
+			return nil, err
+		}
+	}
+	if p := e.find(dirs, textFile); p != "" {
+		if set.text, err = e.parseText(p, true); err != nil {
+			return nil, err
+		}
+	}
+
+	actual, _ := e.cache.LoadOrStore(key, set)
+	return actual.(*templateSet), nil
+}
+
+// localeDirs lists the directories searched for a template, most specific
+// first.
+func localeDirs(code, locale string) []string {
+	dirs := make([]string, 0, 3)
+	locale = strings.ReplaceAll(locale, "_", "-")
+	if locale != "" && fs.ValidPath(locale) {
+		dirs = append(dirs, path.Join(code, locale))
+		if lang, _, ok := strings.Cut(locale, "-"); ok {
+			dirs = append(dirs, path.Join(code, lang))
+		}
+	}
+	return append(dirs, code)
+}
+
+func (e *Engine) find(dirs []string, name string) string {
+	for _, dir := range dirs {
+		p := path.Join(dir, name)
+		if _, err := fs.Stat(e.fsys, p); err == nil {
+			return p
+		}
+	}
+	return ""
+}
+
+func (e *Engine) shared(ext string) ([]string, error) {
+	var files []string
+	for _, dir := range []string{"layouts", "partials"} {
+		matches, err := fs.Glob(e.fsys, dir+"/*"+ext)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	return files, nil
+}
+
+func (e *Engine) parseHTML(p string) (*htmltemplate.Template, error) {
+	shared, err := e.shared(".html")
+	if err != nil {
+		return nil, err
+	}
+	t := htmltemplate.New(path.Base(p)).Funcs(e.funcs)
+	if len(shared) > 0 {
+		if t, err = t.ParseFS(e.fsys, shared...); err != nil {
+			return nil, err
+		}
+	}
+	return t.ParseFS(e.fsys, p)
+}
+
+func (e *Engine) parseText(p string, withShared bool) (*texttemplate.Template, error) {
+	t := texttemplate.New(path.Base(p)).Funcs(e.funcs)
+	if withShared {
+		shared, err := e.shared(".txt")
+		if err != nil {
+			return nil, err
+		}
+		if len(shared) > 0 {
+			if t, err = t.ParseFS(e.fsys, shared...); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return t.ParseFS(e.fsys, p)
+}
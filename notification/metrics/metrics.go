@@ -0,0 +1,92 @@
+// Package metrics records what the gateways send. Gateways report to a
+// Metrics, which defaults to Nop; Prometheus keeps counters and histograms
+// per channel and endpoint and serves them in the Prometheus text format.
+//
+//	m := metrics.NewPrometheus("notif")
+//	http.Handle("/metrics", m)
+//
+//	bellHandler, err := bell.NewNotifBellApiHandler(option.WithMetrics(m))
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/textproto"
+	"time"
+
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/httpstatus"
+)
+
+// Failure reasons reported by the gateways.
+const (
+	ReasonValidation = "validation"
+	ReasonTimeout    = "timeout"
+	ReasonCanceled   = "canceled"
+	ReasonNetwork    = "network"
+	ReasonStatus     = "status"
+	ReasonSMTP       = "smtp"
+	ReasonOther      = "other"
+)
+
+type Metrics interface {
+	// Sent records a successful send to recipients.
+	Sent(channel, endpoint string, recipients int, latency time.Duration)
+	// Failed records a failed send, reason being one of the Reason
+	// constants.
+	Failed(channel, endpoint, reason string, latency time.Duration)
+	// Retried records a retry of a send.
+	Retried(channel, endpoint string)
+	// Batch records the number of messages in a bulk request.
+	Batch(channel, endpoint string, size int)
+}
+
+// Nop discards every metric.
+type Nop struct{}
+
+func (Nop) Sent(channel, endpoint string, recipients int, latency time.Duration) {}
+func (Nop) Failed(channel, endpoint, reason string, latency time.Duration)       {}
+func (Nop) Retried(channel, endpoint string)                                     {}
+func (Nop) Batch(channel, endpoint string, size int)                             {}
+
+// ValidationError is the error of a payload rejected before it was sent.
+type ValidationError struct {
+	Err error
+}
+
+func (e *ValidationError) Error() string { return e.Err.Error() }
+func (e *ValidationError) Unwrap() error { return e.Err }
+
+// Invalid marks err as a ValidationError. It returns nil when err is nil.
+func Invalid(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &ValidationError{Err: err}
+}
+
+// Reason classifies err into a failure reason.
+func Reason(err error) string {
+	var netErr net.Error
+	var smtpErr *textproto.Error
+	var statusErr *httpstatus.Error
+	var validationErr *ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		return ReasonValidation
+	case errors.As(err, &statusErr):
+		return ReasonStatus
+	case errors.Is(err, context.DeadlineExceeded):
+		return ReasonTimeout
+	case errors.Is(err, context.Canceled):
+		return ReasonCanceled
+	case errors.As(err, &netErr):
+		if netErr.Timeout() {
+			return ReasonTimeout
+		}
+		return ReasonNetwork
+	case errors.As(err, &smtpErr):
+		return ReasonSMTP
+	}
+	return ReasonOther
+}
@@ -0,0 +1,199 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultLatencyBuckets are the upper bounds, in seconds, of the latency
+// histogram.
+var DefaultLatencyBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// DefaultBatchBuckets are the upper bounds of the batch size histogram.
+var DefaultBatchBuckets = []float64{1, 2, 5, 10, 25, 50, 100, 250, 500, 1000}
+
+// Prometheus is a Metrics keeping its values in memory. It serves them in
+// the Prometheus text exposition format as an http.Handler.
+type Prometheus struct {
+	mu         sync.Mutex
+	sends      *vec
+	recipients *vec
+	failures   *vec
+	retries    *vec
+	latency    *vec
+	batches    *vec
+}
+
+// NewPrometheus returns a Prometheus whose metric names start with
+// namespace, e.g. "notif" for notif_sends_total.
+func NewPrometheus(namespace string) *Prometheus {
+	name := func(s string) string {
+		if namespace == "" {
+			return s
+		}
+		return namespace + "_" + s
+	}
+	return &Prometheus{
+		sends:      newVec(name("sends_total"), "Sends that succeeded.", "counter", nil, "channel", "endpoint"),
+		recipients: newVec(name("recipients_total"), "Recipients of the sends that succeeded.", "counter", nil, "channel", "endpoint"),
+		failures:   newVec(name("failures_total"), "Sends that failed, by reason.", "counter", nil, "channel", "endpoint", "reason"),
+		retries:    newVec(name("retries_total"), "Sends that were retried.", "counter", nil, "channel", "endpoint"),
+		latency:    newVec(name("send_duration_seconds"), "Duration of the sends.", "histogram", DefaultLatencyBuckets, "channel", "endpoint"),
+		batches:    newVec(name("batch_size"), "Number of messages in bulk requests.", "histogram", DefaultBatchBuckets, "channel", "endpoint"),
+	}
+}
+
+func (p *Prometheus) Sent(channel, endpoint string, recipients int, latency time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sends.add(1, channel, endpoint)
+	p.recipients.add(float64(recipients), channel, endpoint)
+	p.latency.observe(latency.Seconds(), channel, endpoint)
+}
+
+func (p *Prometheus) Failed(channel, endpoint, reason string, latency time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.failures.add(1, channel, endpoint, reason)
+	p.latency.observe(latency.Seconds(), channel, endpoint)
+}
+
+func (p *Prometheus) Retried(channel, endpoint string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.retries.add(1, channel, endpoint)
+}
+
+func (p *Prometheus) Batch(channel, endpoint string, size int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.batches.observe(float64(size), channel, endpoint)
+}
+
+// WriteTo writes the metrics in the Prometheus text exposition format.
+func (p *Prometheus) WriteTo(w io.Writer) (int64, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+	for _, v := range []*vec{p.sends, p.recipients, p.failures, p.retries, p.latency, p.batches} {
+		v.write(cw)
+	}
+	if cw.err == nil {
+		cw.err = cw.w.Flush()
+	}
+	return cw.n, cw.err
+}
+
+func (p *Prometheus) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	p.WriteTo(w)
+}
+
+// vec is a counter or histogram with labels.
+type vec struct {
+	name    string
+	help    string
+	kind    string
+	buckets []float64
+	labels  []string
+	series  map[string]*series
+}
+
+type series struct {
+	labelValues []string
+	value       float64
+	counts      []uint64
+	count       uint64
+}
+
+func newVec(name, help, kind string, buckets []float64, labels ...string) *vec {
+	return &vec{name: name, help: help, kind: kind, buckets: buckets, labels: labels, series: make(map[string]*series)}
+}
+
+func (v *vec) get(labelValues []string) *series {
+	key := strings.Join(labelValues, "\xff")
+	s, ok := v.series[key]
+	if !ok {
+		s = &series{labelValues: labelValues, counts: make([]uint64, len(v.buckets))}
+		v.series[key] = s
+	}
+	return s
+}
+
+func (v *vec) add(delta float64, labelValues ...string) {
+	v.get(labelValues).value += delta
+}
+
+func (v *vec) observe(value float64, labelValues ...string) {
+	s := v.get(labelValues)
+	for i, bound := range v.buckets {
+		if value <= bound {
+			s.counts[i]++
+		}
+	}
+	s.count++
+	s.value += value
+}
+
+func (v *vec) write(w *countingWriter) {
+	if len(v.series) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", v.name, v.help, v.name, v.kind)
+	keys := make([]string, 0, len(v.series))
+	for key := range v.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		s := v.series[key]
+		labels := v.formatLabels(s.labelValues)
+		if v.kind != "histogram" {
+			fmt.Fprintf(w, "%s{%s} %s\n", v.name, labels, formatFloat(s.value))
+			continue
+		}
+		for i, bound := range v.buckets {
+			fmt.Fprintf(w, "%s_bucket{%s,le=\"%s\"} %d\n", v.name, labels, formatFloat(bound), s.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket{%s,le=\"+Inf\"} %d\n", v.name, labels, s.count)
+		fmt.Fprintf(w, "%s_sum{%s} %s\n", v.name, labels, formatFloat(s.value))
+		fmt.Fprintf(w, "%s_count{%s} %d\n", v.name, labels, s.count)
+	}
+}
+
+func (v *vec) formatLabels(values []string) string {
+	pairs := make([]string, len(v.labels))
+	for i, label := range v.labels {
+		pairs[i] = label + `="` + labelEscaper.Replace(values[i]) + `"`
+	}
+	return strings.Join(pairs, ",")
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	c.err = err
+	return n, err
+}
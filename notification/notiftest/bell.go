@@ -0,0 +1,76 @@
+package notiftest
+
+import (
+	"context"
+	"errors"
+
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/bell"
+)
+
+var _ bell.NotifBellClient = (*Bell)(nil)
+
+// BellCall is a recorded call to Bell. UserIdentifiers and Payloads are set
+// for SendBellBroadcast, Payload for SendBell.
+type BellCall struct {
+	Ctx             context.Context
+	Method          string
+	Payload         bell.NotificationPayload
+	UserIdentifiers []bell.UserIdentifier
+	Payloads        []bell.NotificationPayload
+}
+
+// Bell is a recording bell.NotifBellClient. Deliveries are recorded per
+// user, with broadcasts expanded the way the real gateways do.
+type Bell struct {
+	recorder[BellCall, bell.NotificationPayload]
+}
+
+func NewBell() *Bell {
+	return &Bell{}
+}
+
+func (b *Bell) SendBell(ctx context.Context, payload bell.NotificationPayload) error {
+	b.record(BellCall{Ctx: ctx, Method: "SendBell", Payload: payload})
+	return b.deliver(payload.UserID, payload)
+}
+
+func (b *Bell) SendBellBroadcast(ctx context.Context, userIdentifiers []bell.UserIdentifier, payloads []bell.NotificationPayload) error {
+	b.record(BellCall{Ctx: ctx, Method: "SendBellBroadcast", UserIdentifiers: userIdentifiers, Payloads: payloads})
+
+	var errs []error
+	if len(userIdentifiers) == 0 {
+		for _, payload := range payloads {
+			errs = append(errs, b.deliver(payload.UserID, payload))
+		}
+		return errors.Join(errs...)
+	}
+	if len(payloads) == 0 {
+		return errors.New("no payload to broadcast")
+	}
+	for _, user := range userIdentifiers {
+		payload := payloads[0]
+		payload.UserID = user.UserID
+		errs = append(errs, b.deliver(user.UserID, payload))
+	}
+	return errors.Join(errs...)
+}
+
+// BellToUser matches notifications sent to userID.
+func BellToUser(userID string) Matcher[bell.NotificationPayload] {
+	return Match("user "+userID, func(p bell.NotificationPayload) bool { return p.UserID == userID })
+}
+
+// BellMsgType matches notifications with the given MsgType.
+func BellMsgType(msgType string) Matcher[bell.NotificationPayload] {
+	return Match("msg type "+msgType, func(p bell.NotificationPayload) bool { return p.MsgType == msgType })
+}
+
+// BellType matches notifications with the given Type.
+func BellType(notifType string) Matcher[bell.NotificationPayload] {
+	return Match("type "+notifType, func(p bell.NotificationPayload) bool { return p.Type == notifType })
+}
+
+// BellChannel matches notifications sent on channel.
+func BellChannel(channel string) Matcher[bell.NotificationPayload] {
+	return Match("channel "+channel, func(p bell.NotificationPayload) bool { return p.Channel == channel })
+}
@@ -0,0 +1,116 @@
+package notiftest
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/mailer"
+)
+
+var _ mailer.SmtpClient = (*Mailer)(nil)
+
+// MailerCall is a recorded call to Mailer. FilePaths is set for
+// SendEmailWithFilePaths.
+type MailerCall struct {
+	Ctx       context.Context
+	Method    string
+	Mail      mailer.Mail
+	FilePaths []string
+}
+
+// EmailDelivery is an email as received by one of its To, CC or BCC
+// recipients.
+type EmailDelivery struct {
+	Recipient string
+	Mail      mailer.Mail
+}
+
+// Mailer is a recording mailer.SmtpClient. Like an SMTP relay rejecting a
+// recipient, an error injected for any recipient fails the whole email.
+type Mailer struct {
+	recorder[MailerCall, EmailDelivery]
+}
+
+func NewMailer() *Mailer {
+	return &Mailer{}
+}
+
+func (m *Mailer) SendEmailWithFilePaths(ctx context.Context, mail mailer.MailWithoutAttachments, filePaths []string) (interface{}, error) {
+	attachments := make([]mailer.Attachment, len(filePaths))
+	for i, filePath := range filePaths {
+		attachments[i] = mailer.Attachment{FileName: filepath.Base(filePath), Path: filePath}
+	}
+	converted := mailer.Mail{
+		To:           mail.To,
+		Subject:      mail.Subject,
+		TemplateCode: mail.Message,
+		Data:         map[string]interface{}{"text": mail.Text},
+		Attachments:  attachments,
+		TextBody:     mail.Text,
+	}
+	m.record(MailerCall{Ctx: ctx, Method: "SendEmailWithFilePaths", Mail: converted, FilePaths: filePaths})
+	return m.send(converted)
+}
+
+func (m *Mailer) SendEmail(ctx context.Context, mail mailer.Mail) (interface{}, error) {
+	m.record(MailerCall{Ctx: ctx, Method: "SendEmail", Mail: mail})
+	return m.send(mail)
+}
+
+func (m *Mailer) send(mail mailer.Mail) (interface{}, error) {
+	var recipients []string
+	for _, list := range [][]string{mail.To, mail.CC, mail.BCC} {
+		recipients = append(recipients, list...)
+	}
+	if len(recipients) == 0 {
+		return "Failed", errors.New("no recipients")
+	}
+
+	var errs []error
+	for _, recipient := range recipients {
+		errs = append(errs, m.check(recipient))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return "Failed", err
+	}
+	for _, recipient := range recipients {
+		if err := m.deliver(recipient, EmailDelivery{Recipient: recipient, Mail: mail}); err != nil {
+			return "Failed", err
+		}
+	}
+	return "OKAY", nil
+}
+
+// EmailTo matches emails received by address.
+func EmailTo(address string) Matcher[EmailDelivery] {
+	return Match("recipient "+address, func(d EmailDelivery) bool { return strings.EqualFold(d.Recipient, address) })
+}
+
+// EmailSubject matches emails with the given subject.
+func EmailSubject(subject string) Matcher[EmailDelivery] {
+	return Match("subject "+subject, func(d EmailDelivery) bool { return d.Mail.Subject == subject })
+}
+
+// EmailTemplate matches emails using the template code.
+func EmailTemplate(templateCode string) Matcher[EmailDelivery] {
+	return Match("template "+templateCode, func(d EmailDelivery) bool { return d.Mail.TemplateCode == templateCode })
+}
+
+// EmailData matches emails whose template data has value under key.
+func EmailData(key string, value interface{}) Matcher[EmailDelivery] {
+	return Match("data "+key, func(d EmailDelivery) bool { return d.Mail.Data[key] == value })
+}
+
+// EmailAttachment matches emails carrying an attachment named fileName.
+func EmailAttachment(fileName string) Matcher[EmailDelivery] {
+	return Match("attachment "+fileName, func(d EmailDelivery) bool {
+		for _, attachment := range d.Mail.Attachments {
+			if attachment.FileName == fileName {
+				return true
+			}
+		}
+		return false
+	})
+}
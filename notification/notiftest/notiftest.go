@@ -0,0 +1,163 @@
+// Package notiftest provides in-memory recording fakes for every client
+// interface of the library, so services can be unit tested without
+// hand-written mocks.
+//
+//	notifier := notiftest.NewBell()
+//	notifier.FailFor("user-2", errors.New("unreachable"))
+//
+//	svc := NewOrderService(notifier)
+//	svc.PlaceOrder(ctx, order)
+//
+//	notifier.Expect(t, notiftest.BellToUser("user-1"), notiftest.BellMsgType("order"))
+package notiftest
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Matcher matches a recorded delivery of type T.
+type Matcher[T any] struct {
+	Description string
+	Match       func(T) bool
+}
+
+// Match builds a Matcher from an arbitrary predicate.
+func Match[T any](description string, match func(T) bool) Matcher[T] {
+	return Matcher[T]{Description: description, Match: match}
+}
+
+// recorder holds the calls C made to a fake and the per-recipient
+// deliveries T they produced. Errors can be injected per recipient.
+type recorder[C, T any] struct {
+	mu     sync.Mutex
+	calls  []C
+	sent   []T
+	err    error
+	errFor map[string]error
+}
+
+// FailAll makes every delivery fail with err. A nil err restores success.
+func (r *recorder[C, T]) FailAll(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.err = err
+}
+
+// FailFor makes deliveries to recipient fail with err.
+func (r *recorder[C, T]) FailFor(recipient string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.errFor == nil {
+		r.errFor = make(map[string]error)
+	}
+	r.errFor[recipient] = err
+}
+
+// Reset clears recorded deliveries and injected errors.
+func (r *recorder[C, T]) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = nil
+	r.sent = nil
+	r.err = nil
+	r.errFor = nil
+}
+
+// Calls returns the calls made to the fake, including failed ones.
+func (r *recorder[C, T]) Calls() []C {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]C(nil), r.calls...)
+}
+
+// Sent returns the successful deliveries, one per recipient.
+func (r *recorder[C, T]) Sent() []T {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]T(nil), r.sent...)
+}
+
+func (r *recorder[C, T]) record(c C) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, c)
+}
+
+// check returns the error injected for recipient, if any.
+func (r *recorder[C, T]) check(recipient string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.errFor[recipient]; err != nil {
+		return fmt.Errorf("%s: %w", recipient, err)
+	}
+	return r.err
+}
+
+// deliver records v for recipient unless an error is injected for it.
+func (r *recorder[C, T]) deliver(recipient string, v T) error {
+	if err := r.check(recipient); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sent = append(r.sent, v)
+	return nil
+}
+
+// Filter returns the deliveries matching every matcher.
+func (r *recorder[C, T]) Filter(matchers ...Matcher[T]) []T {
+	var matched []T
+	for _, v := range r.Sent() {
+		if matchAll(v, matchers) {
+			matched = append(matched, v)
+		}
+	}
+	return matched
+}
+
+// Expect fails the test unless at least one delivery matches every matcher.
+func (r *recorder[C, T]) Expect(t testing.TB, matchers ...Matcher[T]) {
+	t.Helper()
+	if len(r.Filter(matchers...)) == 0 {
+		t.Errorf("notiftest: no delivery %s among %d sent", describe(matchers), len(r.Sent()))
+	}
+}
+
+// ExpectCount fails the test unless exactly n deliveries match every matcher.
+func (r *recorder[C, T]) ExpectCount(t testing.TB, n int, matchers ...Matcher[T]) {
+	t.Helper()
+	if got := len(r.Filter(matchers...)); got != n {
+		t.Errorf("notiftest: got %d deliveries %s, want %d", got, describe(matchers), n)
+	}
+}
+
+// ExpectNone fails the test if any delivery matches every matcher.
+func (r *recorder[C, T]) ExpectNone(t testing.TB, matchers ...Matcher[T]) {
+	t.Helper()
+	if got := len(r.Filter(matchers...)); got != 0 {
+		t.Errorf("notiftest: got %d unexpected deliveries %s", got, describe(matchers))
+	}
+}
+
+func matchAll[T any](v T, matchers []Matcher[T]) bool {
+	for _, m := range matchers {
+		if !m.Match(v) {
+			return false
+		}
+	}
+	return true
+}
+
+func describe[T any](matchers []Matcher[T]) string {
+	if len(matchers) == 0 {
+		return "at all"
+	}
+	descriptions := make([]string, len(matchers))
+	for i, m := range matchers {
+		descriptions[i] = m.Description
+	}
+	return "with " + strings.Join(descriptions, " and ")
+}
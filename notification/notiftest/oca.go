@@ -0,0 +1,75 @@
+package notiftest
+
+import (
+	"context"
+	"errors"
+
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/oca"
+)
+
+var _ oca.OCAClient = (*OCA)(nil)
+
+// OCACall is a recorded call to OCA.
+type OCACall struct {
+	Ctx  context.Context
+	Body oca.OCA
+	// Bulk is set for calls to SendWhatsappBulk.
+	Bulk oca.OCABulk
+}
+
+// OCA is a recording oca.OCAClient. Each phone number is delivered
+// independently, as the real gateway does.
+type OCA struct {
+	recorder[OCACall, oca.MessageData]
+}
+
+func NewOCA() *OCA {
+	return &OCA{}
+}
+
+func (o *OCA) SendWhatsapp(ctx context.Context, body oca.OCA) (interface{}, error) {
+	o.record(OCACall{Ctx: ctx, Body: body})
+	var errs []error
+	for _, phoneNumber := range body.PhoneNumber {
+		errs = append(errs, o.deliver(phoneNumber, oca.MessageData{PhoneNumber: phoneNumber, Message: body.MessageData}))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"message": "Whatsapp sent successfully",
+		"status":  "success",
+	}, nil
+}
+
+func (o *OCA) SendWhatsappBulk(ctx context.Context, body oca.OCABulk) (oca.BulkResult, error) {
+	o.record(OCACall{Ctx: ctx, Bulk: body})
+	result := oca.BulkResult{Results: make([]oca.RecipientResult, len(body.Recipients))}
+	for i, recipient := range body.Recipients {
+		message := body.MessageData
+		if recipient.Payload != nil {
+			message.Template.Payload = recipient.Payload
+		}
+		result.Results[i] = oca.RecipientResult{PhoneNumber: recipient.PhoneNumber, Status: oca.StatusSent}
+		if err := o.deliver(recipient.PhoneNumber, oca.MessageData{PhoneNumber: recipient.PhoneNumber, Message: message}); err != nil {
+			result.Results[i].Status = oca.StatusFailed
+			result.Results[i].Error = err.Error()
+			result.Failed++
+			continue
+		}
+		result.Sent++
+	}
+	return result, nil
+}
+
+// OCATo matches messages sent to phoneNumber as given by the caller.
+func OCATo(phoneNumber string) Matcher[oca.MessageData] {
+	return Match("phone number "+phoneNumber, func(m oca.MessageData) bool { return m.PhoneNumber == phoneNumber })
+}
+
+// OCATemplate matches messages using the template code ID.
+func OCATemplate(templateCodeID string) Matcher[oca.MessageData] {
+	return Match("template "+templateCodeID, func(m oca.MessageData) bool {
+		return m.Message.Template.TemplateCodeID == templateCodeID
+	})
+}
@@ -0,0 +1,63 @@
+package notiftest
+
+import (
+	"context"
+	"errors"
+
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/whatsapp"
+)
+
+var _ whatsapp.WhatsappClient = (*Whatsapp)(nil)
+
+// WhatsappCall is a recorded call to Whatsapp.
+type WhatsappCall struct {
+	Ctx  context.Context
+	Body whatsapp.Whatsapp
+}
+
+// WhatsappDelivery is a message as sent to one phone number.
+type WhatsappDelivery struct {
+	PhoneNumber string
+	Type        string
+	ID          string
+	Message     string
+}
+
+// Whatsapp is a recording whatsapp.WhatsappClient.
+type Whatsapp struct {
+	recorder[WhatsappCall, WhatsappDelivery]
+}
+
+func NewWhatsapp() *Whatsapp {
+	return &Whatsapp{}
+}
+
+func (w *Whatsapp) SendWhatsapp(ctx context.Context, body whatsapp.Whatsapp) (interface{}, error) {
+	w.record(WhatsappCall{Ctx: ctx, Body: body})
+	var errs []error
+	for _, phoneNumber := range body.To {
+		errs = append(errs, w.deliver(phoneNumber, WhatsappDelivery{
+			PhoneNumber: phoneNumber,
+			Type:        body.Type,
+			ID:          body.ID,
+			Message:     body.Message,
+		}))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"message": "Whatsapp sent successfully",
+		"status":  "success",
+	}, nil
+}
+
+// WhatsappTo matches messages sent to phoneNumber as given by the caller.
+func WhatsappTo(phoneNumber string) Matcher[WhatsappDelivery] {
+	return Match("phone number "+phoneNumber, func(d WhatsappDelivery) bool { return d.PhoneNumber == phoneNumber })
+}
+
+// WhatsappType matches messages of the given type.
+func WhatsappType(messageType string) Matcher[WhatsappDelivery] {
+	return Match("type "+messageType, func(d WhatsappDelivery) bool { return d.Type == messageType })
+}
@@ -0,0 +1,60 @@
+package oca
+
+import (
+	"context"
+	"sync"
+)
+
+// message returns the message of recipient.
+func (b OCABulk) message(recipient Recipient) Message {
+	message := b.MessageData
+	if recipient.Payload != nil {
+		message.Template.Payload = recipient.Payload
+	}
+	return message
+}
+
+// sendAll calls send for every recipient, running at most concurrency
+// calls at once, or all of them when concurrency is 0. It returns the
+// message IDs and errors in the order of recipients.
+func sendAll(ctx context.Context, recipients []Recipient, concurrency int, send func(ctx context.Context, recipient Recipient) (string, error)) ([]string, []error) {
+	messageIDs := make([]string, len(recipients))
+	errs := make([]error, len(recipients))
+	var sem chan struct{}
+	if concurrency > 0 {
+		sem = make(chan struct{}, concurrency)
+	}
+	var wg sync.WaitGroup
+	for i, recipient := range recipients {
+		if sem != nil {
+			sem <- struct{}{}
+		}
+		wg.Add(1)
+		go func(i int, recipient Recipient) {
+			defer wg.Done()
+			if sem != nil {
+				defer func() { <-sem }()
+			}
+			messageIDs[i], errs[i] = send(ctx, recipient)
+		}(i, recipient)
+	}
+	wg.Wait()
+	return messageIDs, errs
+}
+
+// bulkResult returns the result of sending to recipients.
+func bulkResult(recipients []Recipient, messageIDs []string, errs []error) BulkResult {
+	bulk := BulkResult{Results: make([]RecipientResult, len(recipients))}
+	for i, recipient := range recipients {
+		result := RecipientResult{PhoneNumber: recipient.PhoneNumber, Status: StatusSent, MessageID: messageIDs[i]}
+		if errs[i] != nil {
+			result.Status = StatusFailed
+			result.Error = errs[i].Error()
+			bulk.Failed++
+		} else {
+			bulk.Sent++
+		}
+		bulk.Results[i] = result
+	}
+	return bulk
+}
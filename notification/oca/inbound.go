@@ -0,0 +1,218 @@
+package oca
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/phone"
+)
+
+// Types of an InboundMessage.
+const (
+	InboundText     = "text"
+	InboundImage    = "image"
+	InboundDocument = "document"
+	InboundAudio    = "audio"
+	InboundVideo    = "video"
+	InboundSticker  = "sticker"
+	InboundLocation = "location"
+	// InboundButton is a reply button of a template or an interactive
+	// message, or an option of a list.
+	InboundButton = "button"
+)
+
+// InboundMessage is a message sent by a customer.
+type InboundMessage struct {
+	ID string `json:"id"`
+	// From is the phone number of the customer, in E.164 without the
+	// leading "+".
+	From string    `json:"from"`
+	Time time.Time `json:"time"`
+	// Type is one of the Inbound constants, or the type reported by OCA for
+	// other messages.
+	Type string `json:"type"`
+	// Text is the body of text messages and the caption of media.
+	Text     string        `json:"text,omitempty"`
+	Media    *InboundMedia `json:"media,omitempty"`
+	Location *Location     `json:"location,omitempty"`
+	Reply    *Reply        `json:"reply,omitempty"`
+	// ReplyTo is the ID of the message the customer replied to, as
+	// returned by SendWhatsapp.
+	ReplyTo string `json:"reply_to,omitempty"`
+}
+
+// InboundMedia is an image, document, audio, video or sticker. Its
+// content is downloaded from OCA by ID.
+type InboundMedia struct {
+	ID       string `json:"id"`
+	MimeType string `json:"mime_type,omitempty"`
+	SHA256   string `json:"sha256,omitempty"`
+	Caption  string `json:"caption,omitempty"`
+	Filename string `json:"filename,omitempty"`
+}
+
+// Reply is the button or list option chosen by the customer.
+type Reply struct {
+	// Payload is the payload of a template button, or the ID of an
+	// interactive Button or Row.
+	Payload string `json:"payload"`
+	Title   string `json:"title"`
+}
+
+// MessageHandler receives the inbound messages of a Webhook. An error
+// makes the webhook answer 500 so OCA retries the callback, so a message
+// may be handled more than once.
+type MessageHandler interface {
+	HandleMessage(ctx context.Context, message InboundMessage) error
+}
+
+type MessageHandlerFunc func(ctx context.Context, message InboundMessage) error
+
+func (f MessageHandlerFunc) HandleMessage(ctx context.Context, message InboundMessage) error {
+	return f(ctx, message)
+}
+
+// MessageRouter is a MessageHandler routing messages by the payload of
+// the button they reply with, then by the phone number they come from.
+// Messages matching no route go to the default handler, or are dropped.
+type MessageRouter struct {
+	// PhoneRegion is the region of the phone numbers given to HandlePhone
+	// without country code. Defaults to phone.DefaultRegion.
+	PhoneRegion string
+
+	mu       sync.RWMutex
+	payloads map[string]MessageHandler
+	phones   map[string]MessageHandler
+	fallback MessageHandler
+}
+
+func NewMessageRouter() *MessageRouter {
+	return &MessageRouter{
+		payloads: make(map[string]MessageHandler),
+		phones:   make(map[string]MessageHandler),
+	}
+}
+
+// HandlePayload routes replies with the button payload to h.
+func (r *MessageRouter) HandlePayload(payload string, h MessageHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.payloads[payload] = h
+}
+
+// HandlePhone routes messages from phoneNumber to h. It fails when
+// phoneNumber is invalid.
+func (r *MessageRouter) HandlePhone(phoneNumber string, h MessageHandler) error {
+	number, err := phone.Parse(phoneNumber, r.PhoneRegion)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.phones[number.E164()] = h
+	return nil
+}
+
+// HandleDefault routes the messages matching no other route to h.
+func (r *MessageRouter) HandleDefault(h MessageHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fallback = h
+}
+
+func (r *MessageRouter) HandleMessage(ctx context.Context, message InboundMessage) error {
+	if h := r.route(message); h != nil {
+		return h.HandleMessage(ctx, message)
+	}
+	return nil
+}
+
+func (r *MessageRouter) route(message InboundMessage) MessageHandler {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if message.Reply != nil {
+		if h, ok := r.payloads[message.Reply.Payload]; ok {
+			return h
+		}
+	}
+	// Inbound numbers are always international.
+	if number, err := phone.Parse("+"+message.From, ""); err == nil {
+		if h, ok := r.phones[number.E164()]; ok {
+			return h
+		}
+	}
+	return r.fallback
+}
+
+// webhookMessage is an inbound message of a callback.
+type webhookMessage struct {
+	ID        string          `json:"id"`
+	From      string          `json:"from"`
+	Timestamp json.RawMessage `json:"timestamp"`
+	Type      string          `json:"type"`
+	Text      struct {
+		Body string `json:"body"`
+	} `json:"text"`
+	Image    *InboundMedia `json:"image"`
+	Document *InboundMedia `json:"document"`
+	Audio    *InboundMedia `json:"audio"`
+	Video    *InboundMedia `json:"video"`
+	Sticker  *InboundMedia `json:"sticker"`
+	Location *Location     `json:"location"`
+	Button   *struct {
+		Payload string `json:"payload"`
+		Text    string `json:"text"`
+	} `json:"button"`
+	Interactive *struct {
+		ButtonReply *Button `json:"button_reply"`
+		ListReply   *Row    `json:"list_reply"`
+	} `json:"interactive"`
+	Context struct {
+		ID string `json:"id"`
+	} `json:"context"`
+}
+
+func (m webhookMessage) message() InboundMessage {
+	message := InboundMessage{
+		ID:      m.ID,
+		From:    strings.TrimPrefix(m.From, "+"),
+		Time:    parseTimestamp(m.Timestamp),
+		Type:    m.Type,
+		Text:    m.Text.Body,
+		ReplyTo: m.Context.ID,
+	}
+	switch m.Type {
+	case InboundImage:
+		message.Media = m.Image
+	case InboundDocument:
+		message.Media = m.Document
+	case InboundAudio:
+		message.Media = m.Audio
+	case InboundVideo:
+		message.Media = m.Video
+	case InboundSticker:
+		message.Media = m.Sticker
+	case InboundLocation:
+		message.Location = m.Location
+	case InboundButton:
+		if m.Button != nil {
+			message.Reply = &Reply{Payload: m.Button.Payload, Title: m.Button.Text}
+		}
+	case MessageInteractive:
+		message.Type = InboundButton
+		switch {
+		case m.Interactive == nil:
+		case m.Interactive.ButtonReply != nil:
+			message.Reply = &Reply{Payload: m.Interactive.ButtonReply.ID, Title: m.Interactive.ButtonReply.Title}
+		case m.Interactive.ListReply != nil:
+			message.Reply = &Reply{Payload: m.Interactive.ListReply.ID, Title: m.Interactive.ListReply.Title}
+		}
+	}
+	if message.Media != nil && message.Text == "" {
+		message.Text = message.Media.Caption
+	}
+	return message
+}
@@ -0,0 +1,137 @@
+package oca
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// Message types.
+const (
+	MessageTemplate    = "template"
+	MessageDocument    = "document"
+	MessageImage       = "image"
+	MessageLocation    = "location"
+	MessageInteractive = "interactive"
+)
+
+// Interactive message types.
+const (
+	InteractiveButton = "button"
+	InteractiveList   = "list"
+)
+
+// Limits of interactive messages set by WhatsApp.
+const (
+	maxButtons     = 3
+	maxButtonTitle = 20
+	maxRows        = 10
+	maxRowTitle    = 24
+)
+
+// MarshalJSON leaves the template out of messages of other types.
+func (m Message) MarshalJSON() ([]byte, error) {
+	type message Message
+	if m.Type == "" || m.Type == MessageTemplate {
+		return json.Marshal(message(m))
+	}
+	return json.Marshal(struct {
+		message
+		Template *Template `json:"template,omitempty"`
+	}{message: message(m)})
+}
+
+// Validate checks the fields required by the type of m.
+func (m Message) Validate() error {
+	switch m.Type {
+	case "", MessageTemplate:
+		return ValidateTemplateCode(m.Template.TemplateCodeID)
+	case MessageDocument:
+		return validateMedia(m.Type, m.Document)
+	case MessageImage:
+		return validateMedia(m.Type, m.Image)
+	case MessageLocation:
+		return validateLocation(m.Location)
+	case MessageInteractive:
+		return validateInteractive(m.Interactive)
+	}
+	return fmt.Errorf("invalid message type %q", m.Type)
+}
+
+func validateMedia(messageType string, media *Media) error {
+	if media == nil || media.Link == "" {
+		return fmt.Errorf("%s link is required", messageType)
+	}
+	link, err := url.Parse(media.Link)
+	if err != nil || (link.Scheme != "https" && link.Scheme != "http") || link.Host == "" {
+		return fmt.Errorf("invalid %s link", messageType)
+	}
+	return nil
+}
+
+func validateLocation(location *Location) error {
+	if location == nil {
+		return errors.New("location is required")
+	}
+	if location.Latitude < -90 || location.Latitude > 90 {
+		return errors.New("invalid location latitude")
+	}
+	if location.Longitude < -180 || location.Longitude > 180 {
+		return errors.New("invalid location longitude")
+	}
+	return nil
+}
+
+func validateInteractive(interactive *Interactive) error {
+	if interactive == nil {
+		return errors.New("interactive is required")
+	}
+	if interactive.Body.Text == "" {
+		return errors.New("interactive body text is required")
+	}
+	action := interactive.Action
+	switch interactive.Type {
+	case InteractiveButton:
+		if len(action.Buttons) == 0 || len(action.Buttons) > maxButtons {
+			return fmt.Errorf("interactive buttons must be 1 to %d, got %d", maxButtons, len(action.Buttons))
+		}
+		for _, button := range action.Buttons {
+			if err := validateOption("button", button.ID, button.Title, maxButtonTitle); err != nil {
+				return err
+			}
+		}
+	case InteractiveList:
+		if action.Button == "" {
+			return errors.New("interactive list button is required")
+		}
+		rows := 0
+		for _, section := range action.Sections {
+			for _, row := range section.Rows {
+				if err := validateOption("row", row.ID, row.Title, maxRowTitle); err != nil {
+					return err
+				}
+				rows++
+			}
+		}
+		if rows == 0 || rows > maxRows {
+			return fmt.Errorf("interactive list rows must be 1 to %d, got %d", maxRows, rows)
+		}
+	default:
+		return fmt.Errorf("invalid interactive type %q", interactive.Type)
+	}
+	return nil
+}
+
+func validateOption(kind, id, title string, maxTitle int) error {
+	if id == "" {
+		return fmt.Errorf("interactive %s id is required", kind)
+	}
+	if title == "" {
+		return fmt.Errorf("interactive %s title is required", kind)
+	}
+	if n := len([]rune(title)); n > maxTitle {
+		return fmt.Errorf("interactive %s title is longer than %d characters", kind, maxTitle)
+	}
+	return nil
+}
@@ -2,6 +2,9 @@ package oca
 
 import "context"
 
+// OCAClient sends WhatsApp messages through OCA. SendWhatsappBulk was added
+// after SendWhatsapp: implementations outside this module must add it.
 type OCAClient interface {
 	SendWhatsapp(ctx context.Context, body OCA) (data interface{}, err error)
+	SendWhatsappBulk(ctx context.Context, body OCABulk) (result BulkResult, err error)
 }
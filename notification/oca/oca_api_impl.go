@@ -5,15 +5,28 @@ import (
 	"context"
 	"encoding/json"
 	"io/ioutil"
-	"log"
+	"log/slog"
 	"net/http"
+	"time"
 
 	cfg "github.com/DamiaRalitsa/notif-lib-golang/notification/config"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/httpstatus"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/internal/report"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/metrics"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/option"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/sandbox"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/tracing"
 )
 
 type gatewayApi struct {
 	FabdBaseUrl string
 	ApiKey      string
+	HTTPClient  *http.Client
+	Sandbox     *sandbox.Sandbox
+	Concurrency int
+	Logger      *slog.Logger
+	Metrics     metrics.Metrics
+	Tracer      *tracing.Tracer
 }
 
 type ApiResponse struct {
@@ -21,35 +34,69 @@ type ApiResponse struct {
 	Message string `json:"message"`
 }
 
-func NewOCAApiHandler() (OCAClient, error) {
+func NewOCAApiHandler(opts ...option.Option) (OCAClient, error) {
 	config, err := cfg.InitEnv(cfg.API)
 	if err != nil {
-		log.Fatalf("Failed to initialize environment: %v", err)
+		return nil, err
+	}
+	o, err := option.New(opts...)
+	if err != nil {
 		return nil, err
 	}
 	g := &gatewayApi{
-		FabdBaseUrl: config.ApiConfig.FabdBaseUrl,
+		FabdBaseUrl: o.BaseURLOr(config.ApiConfig.FabdBaseUrl),
 		ApiKey:      config.ApiConfig.ApiKey,
+		HTTPClient:  o.HTTPClient,
+		Sandbox:     o.Sandbox,
+		Concurrency: o.Concurrency,
+		Logger:      o.Logger,
+		Metrics:     o.Metrics,
+		Tracer:      o.Tracer,
 	}
 	return g, nil
 }
 
 func (g gatewayApi) SendWhatsapp(ctx context.Context, payload OCA) (data interface{}, err error) {
+	ctx, span := g.Tracer.Start(ctx, "oca.SendWhatsapp", sandbox.ChannelOCA,
+		tracing.RecipientsKey.Int(len(payload.PhoneNumber)),
+		tracing.TemplateKey.String(payload.MessageData.Template.TemplateCodeID),
+	)
+	start := time.Now()
 	url := g.FabdBaseUrl + "/v4/webhooks/whatsapp-notification"
+	defer func() {
+		g.Tracer.End(span, err)
+		report.Send(ctx, g.Logger, g.Metrics, sandbox.ChannelOCA, url, len(payload.PhoneNumber), start, err)
+	}()
+	if err := payload.MessageData.Validate(); err != nil {
+		return nil, metrics.Invalid(err)
+	}
+	if g.Sandbox != nil {
+		payload.PhoneNumber = g.Sandbox.Phones(payload.PhoneNumber)
+		err = g.Sandbox.Capture(ctx, sandbox.Record{
+			Channel:    sandbox.ChannelOCA,
+			Endpoint:   url,
+			Recipients: payload.PhoneNumber,
+			Payload:    payload,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return ApiResponse{Status: true, Message: "Whatsapp notification sent (sandbox)"}, nil
+	}
+	g.Metrics.Batch(sandbox.ChannelOCA, url, len(payload.PhoneNumber))
 	jsonData, err := json.Marshal(payload)
 	if err != nil {
 		return nil, err
 	}
-	log.Printf("Payload: %s", string(jsonData))
 
-	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("Authorization", g.ApiKey)
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
+	client := g.HTTPClient
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, err
@@ -61,12 +108,39 @@ func (g gatewayApi) SendWhatsapp(ctx context.Context, payload OCA) (data interfa
 		return nil, err
 	}
 
+	if err := httpstatus.Check(resp, body); err != nil {
+		return nil, err
+	}
+
 	var apiResponse ApiResponse
 	err = json.Unmarshal(body, &apiResponse)
 	if err != nil {
 		return nil, err
 	}
 
-	log.Println("Response from external endpoint:", resp.Status)
+	g.Logger.DebugContext(ctx, "response from external endpoint", "channel", sandbox.ChannelOCA, "endpoint", url, "status", resp.StatusCode)
 	return apiResponse, nil
 }
+
+// SendWhatsappBulk sends one FABD request per recipient, as FABD shares the
+// message data of a request between its phone numbers. Each request is
+// logged and measured as a SendWhatsapp. FABD does not return the ID of
+// the message, so the MessageID of the results is empty.
+func (g gatewayApi) SendWhatsappBulk(ctx context.Context, body OCABulk) (result BulkResult, err error) {
+	ctx, span := g.Tracer.Start(ctx, "oca.SendWhatsappBulk", sandbox.ChannelOCA,
+		tracing.RecipientsKey.Int(len(body.Recipients)),
+		tracing.TemplateKey.String(body.MessageData.Template.TemplateCodeID),
+	)
+	defer func() {
+		g.Tracer.End(span, err)
+	}()
+	if err := body.MessageData.Validate(); err != nil {
+		return BulkResult{}, metrics.Invalid(err)
+	}
+
+	messageIDs, errs := sendAll(ctx, body.Recipients, g.Concurrency, func(ctx context.Context, recipient Recipient) (string, error) {
+		_, err := g.SendWhatsapp(ctx, OCA{PhoneNumber: []string{recipient.PhoneNumber}, MessageData: body.message(recipient)})
+		return "", err
+	})
+	return bulkResult(body.Recipients, messageIDs, errs), nil
+}
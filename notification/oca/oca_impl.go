@@ -4,122 +4,201 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"errors"
-	"log"
+	"log/slog"
 	"net/http"
-	"regexp"
-	"sync"
 	"time"
 
 	cfg "github.com/DamiaRalitsa/notif-lib-golang/notification/config"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/httpstatus"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/internal/report"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/metrics"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/option"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/phone"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/sandbox"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/tracing"
 )
 
 type gateway struct {
 	OCAWABASEURL string
 	OCAWAToken   string
+	HTTPClient   *http.Client
+	Sandbox      *sandbox.Sandbox
+	PhoneRegion  string
+	Concurrency  int
+	Logger       *slog.Logger
+	Metrics      metrics.Metrics
+	Tracer       *tracing.Tracer
 }
 
-func NewOCAHandler() (OCAClient, error) {
+func NewOCAHandler(opts ...option.Option) (OCAClient, error) {
 	config, err := cfg.InitEnv(cfg.OCA)
 	if err != nil {
-		log.Fatalf("Failed to initialize environment: %v", err)
+		return nil, err
+	}
+	o, err := option.New(opts...)
+	if err != nil {
 		return nil, err
 	}
 	g := &gateway{
-		OCAWABASEURL: config.OCAConfig.OCAWABASEURL,
+		OCAWABASEURL: o.BaseURLOr(config.OCAConfig.OCAWABASEURL),
 		OCAWAToken:   config.OCAConfig.OCAWAToken,
+		HTTPClient:   o.HTTPClient,
+		Sandbox:      o.Sandbox,
+		PhoneRegion:  o.PhoneRegion,
+		Concurrency:  o.Concurrency,
+		Logger:       o.Logger,
+		Metrics:      o.Metrics,
+		Tracer:       o.Tracer,
 	}
 	return g, nil
 }
 
 func (g gateway) SendWhatsapp(ctx context.Context, body OCA) (data interface{}, err error) {
+	ctx, span := g.Tracer.Start(ctx, "oca.SendWhatsapp", sandbox.ChannelOCA,
+		tracing.RecipientsKey.Int(len(body.PhoneNumber)),
+		tracing.TemplateKey.String(body.MessageData.Template.TemplateCodeID),
+	)
 	start := time.Now()
-	var wg sync.WaitGroup
-	results := make(chan error, len(body.PhoneNumber))
-
-	for _, phoneNumber := range body.PhoneNumber {
-		wg.Add(1)
-		go func(phoneNumber string) {
-			defer wg.Done()
-
-			checkPhoneNumber := phoneNumber[:2]
-			if checkPhoneNumber == "08" {
-				phoneNumber = "62" + phoneNumber[1:]
-			} else if checkPhoneNumber == "+6" {
-				phoneNumber = phoneNumber[1:]
-			} else if checkPhoneNumber != "62" {
-				results <- errors.New("invalid phone number")
-				return
-			}
-
-			messageData := MessageData{
-				PhoneNumber: phoneNumber,
-				Message:     body.MessageData,
-			}
-
-			templateCode := messageData.Message.Template.TemplateCodeID
-			templateCodePattern := `^[a-f0-9]{8}_[a-f0-9]{4}_[a-f0-9]{4}_[a-f0-9]{4}_[a-f0-9]{12}:[a-z0-9]+$`
-			templateCodeRegex := regexp.MustCompile(templateCodePattern)
-
-			if templateCode == "" {
-				results <- errors.New("template code is required")
-				return
-			}
-			if !templateCodeRegex.MatchString(templateCode) {
-				results <- errors.New("invalid template code")
-				return
-			}
-
-			messageDataJSON, err := json.Marshal(messageData)
-			if err != nil {
-				results <- err
-				return
-			}
-
-			url := g.OCAWABASEURL + "/api/v2/push/message"
-			req, err := http.NewRequest("POST", url, bytes.NewBuffer(messageDataJSON))
-			if err != nil {
-				results <- err
-				return
-			}
-
-			req.Header.Set("Authorization", "Bearer "+g.OCAWAToken)
-			req.Header.Set("Content-Type", "application/json")
-
-			client := &http.Client{}
-			resp, err := client.Do(req)
-			if err != nil {
-				results <- err
-				return
-			}
-			defer resp.Body.Close()
-
-			if resp.StatusCode != http.StatusOK {
-				results <- errors.New("failed to send notification")
-				return
-			}
-
-			results <- nil
-		}(phoneNumber)
-	}
-
-	go func() {
-		wg.Wait()
-		close(results)
+	defer func() {
+		g.Tracer.End(span, err)
+		report.Send(ctx, g.Logger, g.Metrics, sandbox.ChannelOCA, g.OCAWABASEURL+"/api/v2/push/message", len(body.PhoneNumber), start, err)
 	}()
+	if err := body.MessageData.Validate(); err != nil {
+		return nil, metrics.Invalid(err)
+	}
 
-	for res := range results {
-		if res != nil {
-			return nil, res
+	recipients := make([]Recipient, len(body.PhoneNumber))
+	for i, phoneNumber := range body.PhoneNumber {
+		recipients[i] = Recipient{PhoneNumber: phoneNumber}
+	}
+	messageIDs, errs := sendAll(ctx, recipients, g.Concurrency, func(ctx context.Context, recipient Recipient) (string, error) {
+		return g.sendTo(ctx, recipient.PhoneNumber, body.MessageData)
+	})
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
 		}
 	}
 
 	response := map[string]interface{}{
-		"message": "Whatsapp sent successfully",
-		"status":  "success",
+		"message":     "Whatsapp sent successfully",
+		"status":      "success",
+		"message_ids": messageIDs,
 	}
 
-	log.Printf("sendNotif took %v", time.Since(start))
-
 	return response, nil
 }
+
+// SendWhatsappBulk sends the template of body to every recipient with its
+// own parameters. Failed recipients are reported in the result and each
+// counts as a failure in the metrics.
+func (g gateway) SendWhatsappBulk(ctx context.Context, body OCABulk) (result BulkResult, err error) {
+	ctx, span := g.Tracer.Start(ctx, "oca.SendWhatsappBulk", sandbox.ChannelOCA,
+		tracing.RecipientsKey.Int(len(body.Recipients)),
+		tracing.TemplateKey.String(body.MessageData.Template.TemplateCodeID),
+	)
+	start := time.Now()
+	url := g.OCAWABASEURL + "/api/v2/push/message"
+	var errs []error
+	defer func() {
+		g.Tracer.End(span, err)
+		if err != nil {
+			report.Send(ctx, g.Logger, g.Metrics, sandbox.ChannelOCA, url, len(body.Recipients), start, err)
+			return
+		}
+		report.Bulk(ctx, g.Logger, g.Metrics, sandbox.ChannelOCA, url, start, errs)
+	}()
+	if err := body.MessageData.Validate(); err != nil {
+		return BulkResult{}, metrics.Invalid(err)
+	}
+
+	g.Metrics.Batch(sandbox.ChannelOCA, url, len(body.Recipients))
+	var messageIDs []string
+	messageIDs, errs = sendAll(ctx, body.Recipients, g.Concurrency, func(ctx context.Context, recipient Recipient) (string, error) {
+		return g.sendTo(ctx, recipient.PhoneNumber, body.message(recipient))
+	})
+	return bulkResult(body.Recipients, messageIDs, errs), nil
+}
+
+// sendTo sends message to a single phone number and returns the ID OCA
+// gave it, if any.
+func (g gateway) sendTo(ctx context.Context, phoneNumber string, message Message) (messageID string, err error) {
+	ctx, span := g.Tracer.StartRecipient(ctx, "oca.SendWhatsapp.recipient", phoneNumber)
+	defer func() {
+		g.Tracer.End(span, err)
+	}()
+
+	number, err := phone.Parse(phoneNumber, g.PhoneRegion)
+	if err != nil {
+		return "", metrics.Invalid(err)
+	}
+	phoneNumber = number.Digits()
+
+	messageData := MessageData{
+		PhoneNumber: phoneNumber,
+		Message:     message,
+	}
+
+	url := g.OCAWABASEURL + "/api/v2/push/message"
+	if g.Sandbox != nil {
+		messageData.PhoneNumber = g.Sandbox.Phone(messageData.PhoneNumber)
+		return "", g.Sandbox.Capture(ctx, sandbox.Record{
+			Channel:    sandbox.ChannelOCA,
+			Endpoint:   url,
+			Recipients: []string{messageData.PhoneNumber},
+			Payload:    messageData,
+		})
+	}
+
+	messageDataJSON, err := json.Marshal(messageData)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(messageDataJSON))
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+g.OCAWAToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	client := g.HTTPClient
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if err := httpstatus.Check(resp, nil); err != nil {
+		return "", err
+	}
+
+	var response pushResponse
+	_ = json.NewDecoder(resp.Body).Decode(&response)
+	return response.messageID(), nil
+}
+
+// pushResponse holds the message ID of the response of the push endpoint,
+// at the top level, under data, or as the first of messages.
+type pushResponse struct {
+	MessageID string `json:"message_id"`
+	Data      struct {
+		MessageID string `json:"message_id"`
+	} `json:"data"`
+	Messages []struct {
+		ID string `json:"id"`
+	} `json:"messages"`
+}
+
+func (r pushResponse) messageID() string {
+	switch {
+	case r.MessageID != "":
+		return r.MessageID
+	case r.Data.MessageID != "":
+		return r.Data.MessageID
+	case len(r.Messages) > 0:
+		return r.Messages[0].ID
+	}
+	return ""
+}
@@ -10,9 +10,71 @@ type MessageData struct {
 	Message     Message `json:"message"`
 }
 
+// Message is a WhatsApp message. Type selects the field holding its
+// content; an empty Type is a template message.
 type Message struct {
-	Type     string   `json:"type"`
-	Template Template `json:"template"`
+	Type        string       `json:"type"`
+	Template    Template     `json:"template"`
+	Document    *Media       `json:"document,omitempty"`
+	Image       *Media       `json:"image,omitempty"`
+	Location    *Location    `json:"location,omitempty"`
+	Interactive *Interactive `json:"interactive,omitempty"`
+}
+
+// Media is a document or image sent by link.
+type Media struct {
+	Link    string `json:"link"`
+	Caption string `json:"caption,omitempty"`
+	// Filename is shown for documents, e.g. "invoice-1234.pdf".
+	Filename string `json:"filename,omitempty"`
+}
+
+type Location struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Name      string  `json:"name,omitempty"`
+	Address   string  `json:"address,omitempty"`
+}
+
+// Interactive is a message with reply buttons or a list of options.
+type Interactive struct {
+	// Type is InteractiveButton or InteractiveList.
+	Type   string            `json:"type"`
+	Header *InteractiveText  `json:"header,omitempty"`
+	Body   InteractiveText   `json:"body"`
+	Footer *InteractiveText  `json:"footer,omitempty"`
+	Action InteractiveAction `json:"action"`
+}
+
+type InteractiveText struct {
+	Text string `json:"text"`
+}
+
+type InteractiveAction struct {
+	// Buttons are the reply buttons of InteractiveButton messages.
+	Buttons []Button `json:"buttons,omitempty"`
+	// Button is the label of the button opening the list of
+	// InteractiveList messages.
+	Button   string    `json:"button,omitempty"`
+	Sections []Section `json:"sections,omitempty"`
+}
+
+// Button is a reply button. ID is sent back when it is pressed.
+type Button struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+type Section struct {
+	Title string `json:"title,omitempty"`
+	Rows  []Row  `json:"rows"`
+}
+
+// Row is an option of a list. ID is sent back when it is selected.
+type Row struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
 }
 
 type Template struct {
@@ -35,3 +97,44 @@ type SubParameter struct {
 	Index      string      `json:"index"`
 	Parameters []Parameter `json:"parameters"`
 }
+
+// OCABulk sends one template to many recipients, each with its own
+// parameters.
+type OCABulk struct {
+	// MessageData is shared by every recipient. Its Template.Payload is
+	// used for recipients without a Payload.
+	MessageData Message     `json:"message_data"`
+	Recipients  []Recipient `json:"recipients"`
+}
+
+type Recipient struct {
+	PhoneNumber string `json:"phone_number"`
+	// Payload replaces the template parameters of MessageData for this
+	// recipient.
+	Payload []Payload `json:"payload,omitempty"`
+}
+
+// Recipient statuses of a BulkResult.
+const (
+	StatusSent    = "sent"
+	StatusFailed  = "failed"
+	StatusDropped = "dropped"
+)
+
+// BulkResult is the outcome of SendWhatsappBulk, per recipient in the
+// order of OCABulk.Recipients.
+type BulkResult struct {
+	Results []RecipientResult `json:"results"`
+	Sent    int               `json:"sent"`
+	Failed  int               `json:"failed"`
+}
+
+type RecipientResult struct {
+	PhoneNumber string `json:"phone_number"`
+	Status      string `json:"status"`
+	Error       string `json:"error,omitempty"`
+	// MessageID is the ID OCA gave the message, reported again by its
+	// status callbacks. It is empty for sends through FABD, which does not
+	// return it.
+	MessageID string `json:"message_id,omitempty"`
+}
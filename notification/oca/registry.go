@@ -0,0 +1,237 @@
+package oca
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// templateCodePattern matches OCA template code IDs, a UUID with
+// underscores followed by a language code.
+var templateCodePattern = regexp.MustCompile(`^[a-f0-9]{8}_[a-f0-9]{4}_[a-f0-9]{4}_[a-f0-9]{4}_[a-f0-9]{12}:[a-z0-9]+$`)
+
+// numberPattern matches the values of ParamNumber parameters.
+var numberPattern = regexp.MustCompile(`^-?\d+(\.\d+)?$`)
+
+// ValidateTemplateCode checks that code is an OCA template code ID.
+func ValidateTemplateCode(code string) error {
+	if code == "" {
+		return errors.New("template code is required")
+	}
+	if !templateCodePattern.MatchString(code) {
+		return errors.New("invalid template code")
+	}
+	return nil
+}
+
+// Parameter types of a TemplateSpec.
+const (
+	// ParamText accepts any non-empty text.
+	ParamText = "text"
+	// ParamNumber accepts integers and decimals, e.g. "12500" or "3.5".
+	ParamNumber = "number"
+)
+
+// Button sub types of a TemplateSpec.
+const (
+	ButtonURL        = "url"
+	ButtonQuickReply = "quick_reply"
+)
+
+// TemplateSpec declares a WhatsApp template and the parameters it expects.
+type TemplateSpec struct {
+	// Name identifies the template in a Registry, e.g. "otp".
+	Name string
+	// CodeID is the OCA template code ID.
+	CodeID  string
+	Header  []ParamSpec
+	Body    []ParamSpec
+	Buttons []ButtonSpec
+}
+
+// ParamSpec is a named parameter of a template. Parameters are sent in the
+// order they are declared.
+type ParamSpec struct {
+	Name string
+	// Type is ParamText or ParamNumber. Defaults to ParamText.
+	Type string
+}
+
+// ButtonSpec is a button of a template taking parameters.
+type ButtonSpec struct {
+	// SubType is ButtonURL or ButtonQuickReply.
+	SubType string
+	// Index is the position of the button in the template, from 0.
+	Index  int
+	Params []ParamSpec
+}
+
+// Registry holds the templates declared by an application.
+type Registry struct {
+	mu        sync.RWMutex
+	templates map[string]TemplateSpec
+}
+
+func NewRegistry() *Registry {
+	return &Registry{templates: make(map[string]TemplateSpec)}
+}
+
+// Register declares spec. It fails when spec is invalid or its name is
+// already registered.
+func (r *Registry) Register(spec TemplateSpec) error {
+	if err := spec.validate(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.templates[spec.Name]; ok {
+		return fmt.Errorf("template %q is already registered", spec.Name)
+	}
+	r.templates[spec.Name] = spec
+	return nil
+}
+
+// MustRegister is like Register but panics on error, for templates
+// declared at init.
+func (r *Registry) MustRegister(spec TemplateSpec) {
+	if err := r.Register(spec); err != nil {
+		panic(err)
+	}
+}
+
+// Get returns the template registered as name.
+func (r *Registry) Get(name string) (TemplateSpec, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	spec, ok := r.templates[name]
+	return spec, ok
+}
+
+// Build returns the message of the template registered as name, filled
+// with vars. It fails on unknown templates and on missing, extra or
+// invalid variables.
+func (r *Registry) Build(name string, vars map[string]string) (Message, error) {
+	spec, ok := r.Get(name)
+	if !ok {
+		return Message{}, fmt.Errorf("template %q is not registered", name)
+	}
+	return spec.Build(vars)
+}
+
+// Build returns the message of spec filled with vars.
+func (spec TemplateSpec) Build(vars map[string]string) (Message, error) {
+	var missing, invalid []string
+	used := make(map[string]bool, len(vars))
+	params := func(specs []ParamSpec) []Parameter {
+		parameters := make([]Parameter, 0, len(specs))
+		for _, p := range specs {
+			value, ok := vars[p.Name]
+			used[p.Name] = true
+			switch {
+			case !ok || strings.TrimSpace(value) == "":
+				missing = append(missing, p.Name)
+			case p.Type == ParamNumber && !isNumber(value):
+				invalid = append(invalid, p.Name)
+			}
+			parameters = append(parameters, Parameter{Type: ParamText, Text: value})
+		}
+		return parameters
+	}
+
+	var payload []Payload
+	if len(spec.Header) > 0 {
+		payload = append(payload, Payload{Position: "header", Parameters: toInterfaces(params(spec.Header))})
+	}
+	if len(spec.Body) > 0 {
+		payload = append(payload, Payload{Position: "body", Parameters: toInterfaces(params(spec.Body))})
+	}
+	if len(spec.Buttons) > 0 {
+		buttons := make([]interface{}, len(spec.Buttons))
+		for i, button := range spec.Buttons {
+			buttons[i] = SubParameter{SubType: button.SubType, Index: strconv.Itoa(button.Index), Parameters: params(button.Params)}
+		}
+		payload = append(payload, Payload{Position: "button", Parameters: buttons})
+	}
+
+	var extra []string
+	for name := range vars {
+		if !used[name] {
+			extra = append(extra, name)
+		}
+	}
+	var problems []string
+	if len(missing) > 0 {
+		problems = append(problems, "missing "+strings.Join(missing, ", "))
+	}
+	if len(extra) > 0 {
+		sort.Strings(extra)
+		problems = append(problems, "unexpected "+strings.Join(extra, ", "))
+	}
+	if len(invalid) > 0 {
+		problems = append(problems, "invalid number "+strings.Join(invalid, ", "))
+	}
+	if len(problems) > 0 {
+		return Message{}, fmt.Errorf("template %q: %s", spec.Name, strings.Join(problems, "; "))
+	}
+
+	return Message{
+		Type:     MessageTemplate,
+		Template: Template{TemplateCodeID: spec.CodeID, Payload: payload},
+	}, nil
+}
+
+func (spec TemplateSpec) validate() error {
+	if spec.Name == "" {
+		return errors.New("template name is required")
+	}
+	if err := ValidateTemplateCode(spec.CodeID); err != nil {
+		return fmt.Errorf("template %q: %w", spec.Name, err)
+	}
+	seen := make(map[string]bool)
+	check := func(specs []ParamSpec) error {
+		for _, p := range specs {
+			if p.Name == "" {
+				return fmt.Errorf("template %q: parameter name is required", spec.Name)
+			}
+			if seen[p.Name] {
+				return fmt.Errorf("template %q: parameter %q is declared twice", spec.Name, p.Name)
+			}
+			seen[p.Name] = true
+			if p.Type != "" && p.Type != ParamText && p.Type != ParamNumber {
+				return fmt.Errorf("template %q: parameter %q has invalid type %q", spec.Name, p.Name, p.Type)
+			}
+		}
+		return nil
+	}
+	if err := check(spec.Header); err != nil {
+		return err
+	}
+	if err := check(spec.Body); err != nil {
+		return err
+	}
+	for _, button := range spec.Buttons {
+		if button.SubType != ButtonURL && button.SubType != ButtonQuickReply {
+			return fmt.Errorf("template %q: button %d has invalid sub type %q", spec.Name, button.Index, button.SubType)
+		}
+		if err := check(button.Params); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isNumber(value string) bool {
+	return numberPattern.MatchString(value)
+}
+
+func toInterfaces(parameters []Parameter) []interface{} {
+	values := make([]interface{}, len(parameters))
+	for i, p := range parameters {
+		values[i] = p
+	}
+	return values
+}
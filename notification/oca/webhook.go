@@ -0,0 +1,271 @@
+package oca
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/option"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/redact"
+)
+
+// Delivery statuses of a StatusEvent, besides StatusSent and StatusFailed.
+const (
+	StatusDelivered = "delivered"
+	StatusRead      = "read"
+)
+
+// DefaultSignatureHeader carries the signature of webhook requests.
+const DefaultSignatureHeader = "X-Hub-Signature-256"
+
+const defaultMaxBodyBytes = 1 << 20
+
+var errSignature = errors.New("invalid webhook signature")
+
+// StatusEvent is a delivery status reported by OCA for a message.
+type StatusEvent struct {
+	// MessageID is the ID returned for the message by SendWhatsapp, in
+	// message_ids, and by SendWhatsappBulk.
+	MessageID string `json:"message_id"`
+	// PhoneNumber is the recipient of the message.
+	PhoneNumber string `json:"phone_number"`
+	// Status is StatusSent, StatusDelivered, StatusRead or StatusFailed.
+	Status string        `json:"status"`
+	Time   time.Time     `json:"time"`
+	Errors []StatusError `json:"errors,omitempty"`
+}
+
+// StatusError explains a failed delivery.
+type StatusError struct {
+	Code    int    `json:"code"`
+	Title   string `json:"title"`
+	Message string `json:"message,omitempty"`
+}
+
+// StatusHandler receives the status events of a Webhook, e.g. to update
+// an outbox. An error makes the webhook answer 500 so OCA retries the
+// callback, so an event may be handled more than once.
+type StatusHandler interface {
+	HandleStatus(ctx context.Context, event StatusEvent) error
+}
+
+type StatusHandlerFunc func(ctx context.Context, event StatusEvent) error
+
+func (f StatusHandlerFunc) HandleStatus(ctx context.Context, event StatusEvent) error {
+	return f(ctx, event)
+}
+
+// Webhook is the http.Handler receiving OCA callbacks, with delivery
+// statuses and inbound messages. POST requests must be signed with Secret:
+// their SignatureHeader holds the hex HMAC-SHA256 of the body, optionally
+// prefixed with "sha256=". GET requests answer the subscription challenge
+// when VerifyToken is set.
+//
+// Delivery is at least once. A handler error fails the whole callback,
+// which OCA retries with every event it holds, including those already
+// handled, so handlers must be idempotent, e.g. keyed by message ID and
+// status.
+type Webhook struct {
+	// Secret signs the callbacks. Every callback is rejected while it is
+	// empty.
+	Secret string
+	// SignatureHeader defaults to DefaultSignatureHeader.
+	SignatureHeader string
+	// VerifyToken is the token expected by the subscription challenge.
+	VerifyToken string
+	// Statuses receives the delivery statuses.
+	Statuses StatusHandler
+	// Messages receives the messages sent by customers, e.g. a
+	// MessageRouter.
+	Messages MessageHandler
+	// MaxBodyBytes limits the size of callbacks. Defaults to 1 MiB.
+	MaxBodyBytes int64
+	// Logger receives the rejected callbacks and handler errors, masked
+	// with redact.New(). Defaults to a logger discarding everything.
+	Logger *slog.Logger
+}
+
+func (wh *Webhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		wh.challenge(w, r)
+	case http.MethodPost:
+		wh.callback(w, r)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+func (wh *Webhook) challenge(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if wh.VerifyToken == "" || query.Get("hub.mode") != "subscribe" ||
+		!hmac.Equal([]byte(query.Get("hub.verify_token")), []byte(wh.VerifyToken)) {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	_, _ = io.WriteString(w, query.Get("hub.challenge"))
+}
+
+func (wh *Webhook) callback(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	maxBodyBytes := wh.MaxBodyBytes
+	if maxBodyBytes == 0 {
+		maxBodyBytes = defaultMaxBodyBytes
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	if err != nil {
+		wh.logger().WarnContext(ctx, "webhook body rejected", "error", err)
+		http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err := wh.verify(r.Header, body); err != nil {
+		wh.logger().WarnContext(ctx, "webhook signature rejected", "error", err)
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	var payload webhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		wh.logger().WarnContext(ctx, "webhook payload rejected", "error", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	if err := wh.dispatch(ctx, payload); err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// dispatch hands the events of payload to their handlers, stopping at the
+// first error.
+func (wh *Webhook) dispatch(ctx context.Context, payload webhookPayload) error {
+	statuses, messages := payload.events()
+	if wh.Statuses != nil {
+		for _, status := range statuses {
+			event := status.event()
+			if err := wh.Statuses.HandleStatus(ctx, event); err != nil {
+				wh.logger().ErrorContext(ctx, "webhook status handler failed", "message_id", event.MessageID, "status", event.Status, "error", err)
+				return err
+			}
+		}
+	}
+	if wh.Messages != nil {
+		for _, m := range messages {
+			message := m.message()
+			if err := wh.Messages.HandleMessage(ctx, message); err != nil {
+				wh.logger().ErrorContext(ctx, "webhook message handler failed", "message_id", message.ID, "type", message.Type, "error", err)
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// verify checks the signature of body.
+func (wh *Webhook) verify(header http.Header, body []byte) error {
+	if wh.Secret == "" {
+		return errors.New("webhook secret is not configured")
+	}
+	name := wh.SignatureHeader
+	if name == "" {
+		name = DefaultSignatureHeader
+	}
+	signature, err := hex.DecodeString(strings.TrimPrefix(header.Get(name), "sha256="))
+	if err != nil || len(signature) == 0 {
+		return errSignature
+	}
+	mac := hmac.New(sha256.New, []byte(wh.Secret))
+	mac.Write(body)
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return errSignature
+	}
+	return nil
+}
+
+func (wh *Webhook) logger() *slog.Logger {
+	if wh.Logger == nil {
+		return option.DiscardLogger()
+	}
+	return slog.New(redact.NewHandler(wh.Logger.Handler(), nil))
+}
+
+// webhookPayload is a callback, holding statuses and messages at the top
+// level or in the entry/changes/value envelope of the WhatsApp Business
+// API.
+type webhookPayload struct {
+	webhookValue
+	Entry []struct {
+		Changes []struct {
+			Value webhookValue `json:"value"`
+		} `json:"changes"`
+	} `json:"entry"`
+}
+
+type webhookValue struct {
+	Statuses []webhookStatus  `json:"statuses"`
+	Messages []webhookMessage `json:"messages"`
+}
+
+func (p webhookPayload) events() ([]webhookStatus, []webhookMessage) {
+	statuses, messages := p.Statuses, p.Messages
+	for _, entry := range p.Entry {
+		for _, change := range entry.Changes {
+			statuses = append(statuses, change.Value.Statuses...)
+			messages = append(messages, change.Value.Messages...)
+		}
+	}
+	return statuses, messages
+}
+
+type webhookStatus struct {
+	ID          string          `json:"id"`
+	MessageID   string          `json:"message_id"`
+	Status      string          `json:"status"`
+	Timestamp   json.RawMessage `json:"timestamp"`
+	RecipientID string          `json:"recipient_id"`
+	PhoneNumber string          `json:"phone_number"`
+	Errors      []StatusError   `json:"errors"`
+}
+
+func (s webhookStatus) event() StatusEvent {
+	event := StatusEvent{
+		MessageID:   s.MessageID,
+		PhoneNumber: s.PhoneNumber,
+		Status:      strings.ToLower(s.Status),
+		Time:        parseTimestamp(s.Timestamp),
+		Errors:      s.Errors,
+	}
+	if event.MessageID == "" {
+		event.MessageID = s.ID
+	}
+	if event.PhoneNumber == "" {
+		event.PhoneNumber = s.RecipientID
+	}
+	return event
+}
+
+// parseTimestamp reads Unix seconds, as a number or a string, or RFC 3339
+// times. Missing or unreadable timestamps give the current time.
+func parseTimestamp(raw json.RawMessage) time.Time {
+	value := strings.Trim(string(raw), `"`)
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0).UTC()
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t
+	}
+	return time.Now().UTC()
+}
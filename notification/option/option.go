@@ -0,0 +1,257 @@
+// Package option holds the optional settings accepted by the channel
+// constructors, such as mailer.NewMailerHandler.
+package option
+
+import (
+	"context"
+	"crypto/tls"
+	"log/slog"
+	"net/http"
+
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/mailer/dkim"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/mailer/templates"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/metrics"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/phone"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/redact"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/sandbox"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/tracing"
+	watemplates "github.com/DamiaRalitsa/notif-lib-golang/notification/whatsapp/templates"
+)
+
+type Options struct {
+	// HTTPClient sends the requests of the HTTP based gateways.
+	HTTPClient *http.Client
+	// BaseURL overrides the configured FABD or OCA base URL.
+	BaseURL string
+	// Sandbox puts the gateway in dry-run mode when set.
+	Sandbox *sandbox.Sandbox
+	// Templates renders Mail.TemplateCode locally for the SMTP mailer.
+	Templates *templates.Engine
+	// WhatsappTemplates holds the texts of Whatsapp.Type for the WhatsApp
+	// gateway. Defaults to the built-in messages.
+	WhatsappTemplates *watemplates.Catalog
+	// DKIM signs messages sent by the SMTP mailer.
+	DKIM *dkim.Signer
+	// TLSConfig is used for STARTTLS by the SMTP mailer.
+	TLSConfig *tls.Config
+	// Logger receives the gateway logs. Defaults to a logger discarding
+	// everything.
+	Logger *slog.Logger
+	// Redactor masks personal data and secrets in the logs. Defaults to
+	// redact.New().
+	Redactor *redact.Redactor
+	// Metrics records sends, failures and latencies. Defaults to
+	// metrics.Nop.
+	Metrics metrics.Metrics
+	// TracerProvider creates the spans of the sends. Defaults to
+	// otel.GetTracerProvider().
+	TracerProvider trace.TracerProvider
+	// Propagator injects the trace context into HTTP requests. Defaults to
+	// propagation.TraceContext.
+	Propagator propagation.TextMapPropagator
+	// PhoneRegion is the region of phone numbers given without country
+	// code. Defaults to phone.DefaultRegion.
+	PhoneRegion string
+	// Concurrency limits the requests sent at once by the gateways sending
+	// one request per recipient. Zero means no limit.
+	Concurrency int
+	// Tracer is built by New from TracerProvider, Propagator and Redactor.
+	Tracer *tracing.Tracer
+}
+
+type Option func(*Options)
+
+// New applies opts over the default Options. Sandbox mode defaults to the
+// NOTIF_SANDBOX* configuration, which fails New when it is not valid.
+func New(opts ...Option) (Options, error) {
+	var o Options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.Sandbox == nil {
+		s, err := sandbox.FromEnv()
+		if err != nil {
+			return Options{}, err
+		}
+		o.Sandbox = s
+	}
+	return defaults(o), nil
+}
+
+// NewWithoutEnv applies opts over the default Options without reading the
+// environment: sandbox mode is only enabled by WithSandbox.
+func NewWithoutEnv(opts ...Option) Options {
+	var o Options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return defaults(o)
+}
+
+func defaults(o Options) Options {
+	if o.HTTPClient == nil {
+		o.HTTPClient = &http.Client{}
+	}
+	if o.PhoneRegion == "" {
+		o.PhoneRegion = phone.DefaultRegion
+	}
+	if o.Metrics == nil {
+		o.Metrics = metrics.Nop{}
+	}
+	if o.Redactor == nil {
+		o.Redactor = redact.New()
+	}
+	if o.Logger == nil {
+		o.Logger = DiscardLogger()
+	} else {
+		o.Logger = slog.New(redact.NewHandler(o.Logger.Handler(), o.Redactor))
+	}
+	if o.Sandbox != nil {
+		s := *o.Sandbox
+		if s.Store == nil {
+			s.Store = sandbox.LogStore{Logger: o.Logger, Redactor: o.Redactor}
+		}
+		if s.PhoneRegion == "" {
+			s.PhoneRegion = o.PhoneRegion
+		}
+		o.Sandbox = &s
+	}
+	o.Tracer = tracing.New(o.TracerProvider, o.Propagator, o.Redactor)
+	o.HTTPClient = o.Tracer.Client(o.HTTPClient)
+	return o
+}
+
+// BaseURLOr returns the BaseURL override, or configured when none is set.
+func (o Options) BaseURLOr(configured string) string {
+	if o.BaseURL != "" {
+		return o.BaseURL
+	}
+	return configured
+}
+
+// WithHTTPClient sends HTTP requests with client instead of a default
+// http.Client.
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *Options) {
+		o.HTTPClient = client
+	}
+}
+
+// WithBaseURL points the gateway at url instead of the configured FABD or
+// OCA base URL, e.g. a fabdtest.Server.
+func WithBaseURL(url string) Option {
+	return func(o *Options) {
+		o.BaseURL = url
+	}
+}
+
+// WithTemplates renders email templates with engine before sending them
+// over SMTP.
+func WithTemplates(engine *templates.Engine) Option {
+	return func(o *Options) {
+		o.Templates = engine
+	}
+}
+
+// WithWhatsappTemplates makes the WhatsApp gateway take the text of
+// messages from catalog.
+func WithWhatsappTemplates(catalog *watemplates.Catalog) Option {
+	return func(o *Options) {
+		o.WhatsappTemplates = catalog
+	}
+}
+
+// WithDKIM signs messages sent over SMTP with signer, taking precedence over
+// the NOTIF_EMAIL_DKIM_* configuration.
+func WithDKIM(signer *dkim.Signer) Option {
+	return func(o *Options) {
+		o.DKIM = signer
+	}
+}
+
+// WithTLSConfig sets the TLS configuration used when the SMTP server offers
+// STARTTLS, e.g. to trust a private CA.
+func WithTLSConfig(config *tls.Config) Option {
+	return func(o *Options) {
+		o.TLSConfig = config
+	}
+}
+
+// WithSandbox puts the gateway in dry-run mode: payloads are validated and
+// rendered, then captured by s instead of being sent.
+func WithSandbox(s *sandbox.Sandbox) Option {
+	return func(o *Options) {
+		o.Sandbox = s
+	}
+}
+
+// WithLogger logs through logger, e.g. slog.Default(), instead of
+// discarding the logs.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *Options) {
+		o.Logger = logger
+	}
+}
+
+// WithRedactor masks the logs with r, e.g. to mask payload fields beyond
+// redact.DefaultFields.
+func WithRedactor(r *redact.Redactor) Option {
+	return func(o *Options) {
+		o.Redactor = r
+	}
+}
+
+// WithMetrics records the gateway metrics in m, e.g. a
+// metrics.Prometheus.
+func WithMetrics(m metrics.Metrics) Option {
+	return func(o *Options) {
+		o.Metrics = m
+	}
+}
+
+// WithTracerProvider creates the spans of the sends with provider instead
+// of the global otel.GetTracerProvider().
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(o *Options) {
+		o.TracerProvider = provider
+	}
+}
+
+// WithPropagator injects the trace context into HTTP requests with
+// propagator instead of the W3C traceparent header.
+func WithPropagator(propagator propagation.TextMapPropagator) Option {
+	return func(o *Options) {
+		o.Propagator = propagator
+	}
+}
+
+// WithPhoneRegion reads phone numbers given without country code as
+// numbers of region, an ISO 3166-1 code such as "MY".
+func WithPhoneRegion(region string) Option {
+	return func(o *Options) {
+		o.PhoneRegion = region
+	}
+}
+
+// WithConcurrency sends at most n requests at once to the recipients of a
+// message.
+func WithConcurrency(n int) Option {
+	return func(o *Options) {
+		o.Concurrency = n
+	}
+}
+
+// DiscardLogger returns a logger that discards every record.
+func DiscardLogger() *slog.Logger {
+	return slog.New(discardHandler{})
+}
+
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
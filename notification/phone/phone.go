@@ -0,0 +1,139 @@
+// Package phone parses phone numbers into E.164. Numbers are read in
+// international format, with a leading "+" or "00", or as national numbers
+// of a default region. Spaces, dashes, dots and parentheses are ignored.
+//
+//	number, err := phone.Normalize("0812-3456-7890", "ID") // "+6281234567890"
+package phone
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DefaultRegion is the region of national numbers unless another one is
+// configured.
+const DefaultRegion = "ID"
+
+// ErrInvalid is wrapped by the errors of Parse.
+var ErrInvalid = errors.New("invalid phone number")
+
+// Number is a parsed phone number.
+type Number struct {
+	// CountryCode is the calling code, e.g. "62".
+	CountryCode string
+	// National is the national significant number, without trunk prefix.
+	National string
+}
+
+// E164 returns the number in E.164 format, e.g. "+6281234567890".
+func (n Number) E164() string {
+	return "+" + n.CountryCode + n.National
+}
+
+// Digits returns the number in E.164 format without the leading "+", as
+// WhatsApp providers expect it.
+func (n Number) Digits() string {
+	return n.CountryCode + n.National
+}
+
+// Normalize parses number and returns it in E.164 format.
+func Normalize(number, defaultRegion string) (string, error) {
+	n, err := Parse(number, defaultRegion)
+	if err != nil {
+		return "", err
+	}
+	return n.E164(), nil
+}
+
+// Equal reports whether a and b are the same number, ignoring formatting.
+// Numbers that do not parse are compared as written.
+func Equal(a, b, defaultRegion string) bool {
+	na, errA := Parse(a, defaultRegion)
+	nb, errB := Parse(b, defaultRegion)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return na == nb
+}
+
+// Parse parses number, read as a national number of defaultRegion unless
+// it is in international format. An empty defaultRegion means
+// DefaultRegion.
+func Parse(number, defaultRegion string) (Number, error) {
+	digits, international, err := clean(number)
+	if err != nil {
+		return Number{}, err
+	}
+	if digits == "" {
+		return Number{}, fmt.Errorf("%w: empty", ErrInvalid)
+	}
+	if international {
+		return parseInternational(digits)
+	}
+
+	if defaultRegion == "" {
+		defaultRegion = DefaultRegion
+	}
+	region, ok := regions[strings.ToUpper(defaultRegion)]
+	if !ok {
+		return Number{}, fmt.Errorf("%w: unsupported region %q", ErrInvalid, defaultRegion)
+	}
+	// Numbers already carrying the calling code of the region, e.g.
+	// "6281234567890" in Indonesia.
+	if national := strings.TrimPrefix(digits, region.code); national != digits && region.valid(national) {
+		return Number{CountryCode: region.code, National: national}, nil
+	}
+	national := digits
+	if region.trunk != "" {
+		national = strings.TrimPrefix(national, region.trunk)
+	}
+	if err := region.check(national); err != nil {
+		return Number{}, err
+	}
+	return Number{CountryCode: region.code, National: national}, nil
+}
+
+// clean strips the formatting of number, reporting whether it is in
+// international format.
+func clean(number string) (digits string, international bool, err error) {
+	number = strings.TrimSpace(number)
+	if strings.HasPrefix(number, "+") {
+		number, international = number[1:], true
+	}
+	var b strings.Builder
+	for _, r := range number {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == ' ' || r == '-' || r == '.' || r == '(' || r == ')':
+		default:
+			return "", false, fmt.Errorf("%w: unexpected character %q", ErrInvalid, r)
+		}
+	}
+	digits = b.String()
+	if !international && strings.HasPrefix(digits, "00") {
+		digits, international = digits[2:], true
+	}
+	return digits, international, nil
+}
+
+func parseInternational(digits string) (Number, error) {
+	for size := 1; size <= 3 && size < len(digits); size++ {
+		region, ok := codes[digits[:size]]
+		if !ok {
+			continue
+		}
+		national := digits[size:]
+		if err := region.check(national); err != nil {
+			return Number{}, err
+		}
+		return Number{CountryCode: region.code, National: national}, nil
+	}
+	// Countries missing from the table are only checked against the limits
+	// of E.164, so their calling code is kept with the national number.
+	if len(digits) < minDigits || len(digits) > maxDigits {
+		return Number{}, fmt.Errorf("%w: %d digits", ErrInvalid, len(digits))
+	}
+	return Number{National: digits}, nil
+}
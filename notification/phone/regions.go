@@ -0,0 +1,72 @@
+package phone
+
+import "fmt"
+
+// Total digits allowed by E.164 for countries missing from regions.
+const (
+	minDigits = 8
+	maxDigits = 15
+)
+
+type region struct {
+	name  string
+	code  string
+	trunk string
+	// min and max bound the length of national significant numbers.
+	min, max int
+}
+
+func (r region) valid(national string) bool {
+	return len(national) >= r.min && len(national) <= r.max
+}
+
+func (r region) check(national string) error {
+	if !r.valid(national) {
+		return fmt.Errorf("%w: %d digits for region %s, want %d to %d", ErrInvalid, len(national), r.name, r.min, r.max)
+	}
+	return nil
+}
+
+// regions are the supported regions by ISO 3166-1 alpha-2 code.
+var regions = map[string]region{
+	"AE": {name: "AE", code: "971", trunk: "0", min: 8, max: 9},
+	"AU": {name: "AU", code: "61", trunk: "0", min: 9, max: 9},
+	"BN": {name: "BN", code: "673", min: 7, max: 7},
+	"BR": {name: "BR", code: "55", trunk: "0", min: 10, max: 11},
+	"CA": {name: "CA", code: "1", trunk: "1", min: 10, max: 10},
+	"CN": {name: "CN", code: "86", trunk: "0", min: 10, max: 11},
+	"DE": {name: "DE", code: "49", trunk: "0", min: 6, max: 13},
+	"FR": {name: "FR", code: "33", trunk: "0", min: 9, max: 9},
+	"GB": {name: "GB", code: "44", trunk: "0", min: 9, max: 10},
+	"HK": {name: "HK", code: "852", min: 8, max: 8},
+	"ID": {name: "ID", code: "62", trunk: "0", min: 8, max: 12},
+	"IN": {name: "IN", code: "91", trunk: "0", min: 10, max: 10},
+	"JP": {name: "JP", code: "81", trunk: "0", min: 9, max: 10},
+	"KH": {name: "KH", code: "855", trunk: "0", min: 8, max: 9},
+	"KR": {name: "KR", code: "82", trunk: "0", min: 8, max: 10},
+	"LA": {name: "LA", code: "856", trunk: "0", min: 8, max: 10},
+	"MM": {name: "MM", code: "95", trunk: "0", min: 7, max: 10},
+	"MY": {name: "MY", code: "60", trunk: "0", min: 8, max: 10},
+	"NL": {name: "NL", code: "31", trunk: "0", min: 9, max: 9},
+	"NZ": {name: "NZ", code: "64", trunk: "0", min: 8, max: 10},
+	"PH": {name: "PH", code: "63", trunk: "0", min: 8, max: 10},
+	"SA": {name: "SA", code: "966", trunk: "0", min: 8, max: 9},
+	"SG": {name: "SG", code: "65", min: 8, max: 8},
+	"TH": {name: "TH", code: "66", trunk: "0", min: 8, max: 9},
+	"TL": {name: "TL", code: "670", min: 7, max: 8},
+	"TW": {name: "TW", code: "886", trunk: "0", min: 8, max: 9},
+	"US": {name: "US", code: "1", trunk: "1", min: 10, max: 10},
+	"VN": {name: "VN", code: "84", trunk: "0", min: 9, max: 10},
+}
+
+// codes are the regions by calling code. Regions sharing a code, like US
+// and CA, share their rules.
+var codes = func() map[string]region {
+	m := make(map[string]region, len(regions))
+	for _, r := range regions {
+		if _, ok := m[r.code]; !ok || r.name == "US" {
+			m[r.code] = r
+		}
+	}
+	return m
+}()
@@ -0,0 +1,83 @@
+package policy
+
+import (
+	"context"
+
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/bell"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/sandbox"
+)
+
+type policyBell struct {
+	client bell.NotifBellClient
+	policy *Policy
+}
+
+// NewBell wraps client so every notification goes through p. EnvPrefix is
+// applied to string contents only.
+func NewBell(client bell.NotifBellClient, p *Policy) bell.NotifBellClient {
+	return &policyBell{client: client, policy: p}
+}
+
+func (b *policyBell) SendBell(ctx context.Context, payload bell.NotificationPayload) error {
+	payload, ok := b.apply(ctx, payload)
+	if !ok {
+		return nil
+	}
+	return b.client.SendBell(ctx, payload)
+}
+
+func (b *policyBell) SendBellBroadcast(ctx context.Context, userIdentifiers []bell.UserIdentifier, payloads []bell.NotificationPayload) error {
+	if len(userIdentifiers) > 0 {
+		seen := make(map[string]bool, len(userIdentifiers))
+		var allowed []bell.UserIdentifier
+		for _, user := range userIdentifiers {
+			userID, ok := b.policy.UserID(user.UserID)
+			if !ok {
+				b.policy.logger().InfoContext(ctx, "policy dropped recipient", "channel", sandbox.ChannelBell, "recipient", user.UserID)
+				continue
+			}
+			if !seen[userID] {
+				seen[userID] = true
+				allowed = append(allowed, bell.UserIdentifier{UserID: userID})
+			}
+		}
+		if len(allowed) == 0 {
+			return nil
+		}
+		rewritten := make([]bell.NotificationPayload, len(payloads))
+		for i, payload := range payloads {
+			payload.Content = b.prefix(payload.Content)
+			rewritten[i] = payload
+		}
+		return b.client.SendBellBroadcast(ctx, allowed, rewritten)
+	}
+
+	var allowed []bell.NotificationPayload
+	for _, payload := range payloads {
+		if payload, ok := b.apply(ctx, payload); ok {
+			allowed = append(allowed, payload)
+		}
+	}
+	if len(allowed) == 0 {
+		return nil
+	}
+	return b.client.SendBellBroadcast(ctx, nil, allowed)
+}
+
+func (b *policyBell) apply(ctx context.Context, payload bell.NotificationPayload) (bell.NotificationPayload, bool) {
+	userID, ok := b.policy.UserID(payload.UserID)
+	if !ok {
+		b.policy.logger().InfoContext(ctx, "policy dropped recipient", "channel", sandbox.ChannelBell, "recipient", payload.UserID)
+		return payload, false
+	}
+	payload.UserID = userID
+	payload.Content = b.prefix(payload.Content)
+	return payload, true
+}
+
+func (b *policyBell) prefix(content interface{}) interface{} {
+	if text, ok := content.(string); ok {
+		return b.policy.Prefix(text)
+	}
+	return content
+}
@@ -0,0 +1,43 @@
+package policy
+
+import (
+	"context"
+
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/mailer"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/sandbox"
+)
+
+type policyMailer struct {
+	client mailer.SmtpClient
+	policy *Policy
+}
+
+// NewMailer wraps client so every mail goes through p. A mail left without
+// a To recipient is not sent.
+func NewMailer(client mailer.SmtpClient, p *Policy) mailer.SmtpClient {
+	return &policyMailer{client: client, policy: p}
+}
+
+func (m *policyMailer) SendEmailWithFilePaths(ctx context.Context, mail mailer.MailWithoutAttachments, filePaths []string) (interface{}, error) {
+	mail.To = m.policy.Emails(mail.To)
+	if len(mail.To) == 0 {
+		m.policy.logger().InfoContext(ctx, "policy dropped message, no recipient allowed", "channel", sandbox.ChannelEmail, "subject", mail.Subject)
+		return nil, nil
+	}
+	mail.Subject = m.policy.Prefix(mail.Subject)
+	ctx = m.policy.context(ctx)
+	return m.client.SendEmailWithFilePaths(ctx, mail, filePaths)
+}
+
+func (m *policyMailer) SendEmail(ctx context.Context, mail mailer.Mail) (interface{}, error) {
+	mail.To = m.policy.Emails(mail.To)
+	if len(mail.To) == 0 {
+		m.policy.logger().InfoContext(ctx, "policy dropped message, no recipient allowed", "channel", sandbox.ChannelEmail, "subject", mail.Subject)
+		return nil, nil
+	}
+	mail.CC = m.policy.Emails(mail.CC)
+	mail.BCC = m.policy.Emails(mail.BCC)
+	mail.Subject = m.policy.Prefix(mail.Subject)
+	ctx = m.policy.context(ctx)
+	return m.client.SendEmail(ctx, mail)
+}
@@ -0,0 +1,55 @@
+package policy
+
+import (
+	"context"
+
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/oca"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/sandbox"
+)
+
+type policyOCA struct {
+	client oca.OCAClient
+	policy *Policy
+}
+
+// NewOCA wraps client so every message goes through p. OCA messages are
+// templates, so EnvPrefix does not apply to them.
+func NewOCA(client oca.OCAClient, p *Policy) oca.OCAClient {
+	return &policyOCA{client: client, policy: p}
+}
+
+func (o *policyOCA) SendWhatsapp(ctx context.Context, body oca.OCA) (interface{}, error) {
+	body.PhoneNumber = o.policy.PhoneNumbers(body.PhoneNumber)
+	if len(body.PhoneNumber) == 0 {
+		o.policy.logger().InfoContext(ctx, "policy dropped message, no recipient allowed", "channel", sandbox.ChannelOCA, "template_code", body.MessageData.Template.TemplateCodeID)
+		return nil, nil
+	}
+	return o.client.SendWhatsapp(ctx, body)
+}
+
+// SendWhatsappBulk applies p to every recipient. Dropped recipients are
+// reported with oca.StatusDropped, after the results of the others.
+func (o *policyOCA) SendWhatsappBulk(ctx context.Context, body oca.OCABulk) (oca.BulkResult, error) {
+	var allowed []oca.Recipient
+	var dropped []oca.RecipientResult
+	for _, recipient := range body.Recipients {
+		phoneNumber, ok := o.policy.PhoneNumber(recipient.PhoneNumber)
+		if !ok {
+			o.policy.logger().InfoContext(ctx, "policy dropped recipient", "channel", sandbox.ChannelOCA, "recipient", recipient.PhoneNumber)
+			dropped = append(dropped, oca.RecipientResult{PhoneNumber: recipient.PhoneNumber, Status: oca.StatusDropped})
+			continue
+		}
+		recipient.PhoneNumber = phoneNumber
+		allowed = append(allowed, recipient)
+	}
+	var result oca.BulkResult
+	if len(allowed) > 0 {
+		body.Recipients = allowed
+		var err error
+		if result, err = o.client.SendWhatsappBulk(ctx, body); err != nil {
+			return result, err
+		}
+	}
+	result.Results = append(result.Results, dropped...)
+	return result, nil
+}
@@ -0,0 +1,190 @@
+// Package policy applies recipient rules before a message reaches a
+// channel, for non-production environments. Email recipients outside the
+// allowed domains, phone numbers and bell users outside the allowlists are
+// redirected to a catch-all recipient, or dropped when none is configured.
+// Subjects and messages are tagged with an environment prefix. A nil
+// *Policy lets everything through.
+//
+//	p, err := policy.FromEnv()
+//	emails := policy.NewMailer(mailerHandler, p)
+//	whatsapps := policy.NewWhatsapp(whatsappHandler, p)
+package policy
+
+import (
+	"context"
+	"log/slog"
+	"net/mail"
+	"strings"
+
+	cfg "github.com/DamiaRalitsa/notif-lib-golang/notification/config"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/internal/envprefix"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/option"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/phone"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/redact"
+)
+
+type Policy struct {
+	// AllowedEmailDomains lists the domains email may be delivered to. Empty
+	// allows every domain.
+	AllowedEmailDomains []string
+	// AllowedPhoneNumbers lists the phone numbers WhatsApp messages may be
+	// delivered to. Empty allows every number.
+	AllowedPhoneNumbers []string
+	// PhoneRegion is the region of phone numbers written without country
+	// code. Defaults to phone.DefaultRegion.
+	PhoneRegion string
+	// AllowedUserIDs lists the users bell notifications may be delivered
+	// to. Empty allows every user.
+	AllowedUserIDs []string
+
+	// CatchAllEmail, CatchAllPhoneNumber and CatchAllUserID receive what is
+	// not allowed. When empty, recipients that are not allowed are dropped.
+	CatchAllEmail       string
+	CatchAllPhoneNumber string
+	CatchAllUserID      string
+
+	// EnvPrefix is prepended to email subjects and WhatsApp and bell
+	// messages, e.g. "[STAGING]".
+	EnvPrefix string
+
+	// Logger receives the dropped recipients, masked with redact.New().
+	// Defaults to a logger discarding everything.
+	Logger *slog.Logger
+}
+
+// FromEnv returns the policy configured by the NOTIF_POLICY_* variables, or
+// nil when none is set.
+func FromEnv() (*Policy, error) {
+	config, err := cfg.InitEnv(cfg.POLICY)
+	if err != nil {
+		return nil, err
+	}
+	if config.PolicyConfig == (cfg.PolicyConfig{}) {
+		return nil, nil
+	}
+	return &Policy{
+		AllowedEmailDomains: splitList(config.PolicyConfig.AllowedEmailDomains),
+		AllowedPhoneNumbers: splitList(config.PolicyConfig.AllowedPhoneNumbers),
+		AllowedUserIDs:      splitList(config.PolicyConfig.AllowedUserIDs),
+		CatchAllEmail:       config.PolicyConfig.CatchAllEmail,
+		CatchAllPhoneNumber: config.PolicyConfig.CatchAllPhoneNumber,
+		CatchAllUserID:      config.PolicyConfig.CatchAllUserID,
+		EnvPrefix:           config.PolicyConfig.EnvPrefix,
+	}, nil
+}
+
+func splitList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// Email returns the address to deliver to in place of address, and false
+// when it is dropped.
+func (p *Policy) Email(address string) (string, bool) {
+	if p == nil || len(p.AllowedEmailDomains) == 0 {
+		return address, true
+	}
+	spec := address
+	if parsed, err := mail.ParseAddress(address); err == nil {
+		spec = parsed.Address
+	}
+	if at := strings.LastIndex(spec, "@"); at >= 0 {
+		domain := spec[at+1:]
+		for _, allowed := range p.AllowedEmailDomains {
+			if strings.EqualFold(domain, allowed) {
+				return address, true
+			}
+		}
+	}
+	return redirect(p.CatchAllEmail)
+}
+
+// Emails applies Email to addresses, dropping the resulting duplicates.
+func (p *Policy) Emails(addresses []string) []string {
+	return p.apply(addresses, p.Email)
+}
+
+// PhoneNumber returns the number to deliver to in place of phoneNumber, and
+// false when it is dropped.
+func (p *Policy) PhoneNumber(phoneNumber string) (string, bool) {
+	if p == nil || len(p.AllowedPhoneNumbers) == 0 {
+		return phoneNumber, true
+	}
+	for _, allowed := range p.AllowedPhoneNumbers {
+		if phone.Equal(allowed, phoneNumber, p.PhoneRegion) {
+			return phoneNumber, true
+		}
+	}
+	return redirect(p.CatchAllPhoneNumber)
+}
+
+// PhoneNumbers applies PhoneNumber to phoneNumbers, dropping the resulting
+// duplicates.
+func (p *Policy) PhoneNumbers(phoneNumbers []string) []string {
+	return p.apply(phoneNumbers, p.PhoneNumber)
+}
+
+// UserID returns the user to deliver to in place of userID, and false when
+// it is dropped.
+func (p *Policy) UserID(userID string) (string, bool) {
+	if p == nil || len(p.AllowedUserIDs) == 0 {
+		return userID, true
+	}
+	for _, allowed := range p.AllowedUserIDs {
+		if allowed == userID {
+			return userID, true
+		}
+	}
+	return redirect(p.CatchAllUserID)
+}
+
+// Prefix prepends EnvPrefix to text unless it is empty or already
+// prefixed.
+func (p *Policy) Prefix(text string) string {
+	if p == nil {
+		return text
+	}
+	return envprefix.Add(p.EnvPrefix, text)
+}
+
+// context passes EnvPrefix to the gateway, which prefixes the texts it
+// renders from templates.
+func (p *Policy) context(ctx context.Context) context.Context {
+	if p == nil {
+		return ctx
+	}
+	return envprefix.With(ctx, p.EnvPrefix)
+}
+
+func (p *Policy) logger() *slog.Logger {
+	if p == nil || p.Logger == nil {
+		return option.DiscardLogger()
+	}
+	return slog.New(redact.NewHandler(p.Logger.Handler(), nil))
+}
+
+func redirect(catchAll string) (string, bool) {
+	return catchAll, catchAll != ""
+}
+
+func (p *Policy) apply(recipients []string, rule func(string) (string, bool)) []string {
+	seen := make(map[string]bool, len(recipients))
+	var applied []string
+	for _, recipient := range recipients {
+		rewritten, ok := rule(recipient)
+		if !ok {
+			p.logger().Info("policy dropped recipient", "recipient", recipient)
+			continue
+		}
+		if !seen[rewritten] {
+			seen[rewritten] = true
+			applied = append(applied, rewritten)
+		}
+	}
+	return applied
+}
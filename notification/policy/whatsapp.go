@@ -0,0 +1,28 @@
+package policy
+
+import (
+	"context"
+
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/sandbox"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/whatsapp"
+)
+
+type policyWhatsapp struct {
+	client whatsapp.WhatsappClient
+	policy *Policy
+}
+
+// NewWhatsapp wraps client so every message goes through p.
+func NewWhatsapp(client whatsapp.WhatsappClient, p *Policy) whatsapp.WhatsappClient {
+	return &policyWhatsapp{client: client, policy: p}
+}
+
+func (w *policyWhatsapp) SendWhatsapp(ctx context.Context, body whatsapp.Whatsapp) (interface{}, error) {
+	body.To = w.policy.PhoneNumbers(body.To)
+	if len(body.To) == 0 {
+		w.policy.logger().InfoContext(ctx, "policy dropped message, no recipient allowed", "channel", sandbox.ChannelWhatsapp, "id", body.ID)
+		return nil, nil
+	}
+	body.Message = w.policy.Prefix(body.Message)
+	return w.client.SendWhatsapp(w.policy.context(ctx), body)
+}
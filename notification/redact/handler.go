@@ -0,0 +1,67 @@
+package redact
+
+import (
+	"context"
+	"log/slog"
+)
+
+type handler struct {
+	next     slog.Handler
+	redactor *Redactor
+}
+
+// NewHandler returns a slog.Handler masking the message and attributes of
+// every record with r before passing it to next. A nil r uses New().
+func NewHandler(next slog.Handler, r *Redactor) slog.Handler {
+	if h, ok := next.(*handler); ok {
+		next = h.next
+	}
+	if r == nil {
+		r = New()
+	}
+	return &handler{next: next, redactor: r}
+}
+
+func (h *handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *handler) Handle(ctx context.Context, record slog.Record) error {
+	redacted := slog.NewRecord(record.Time, record.Level, h.redactor.String(record.Message), record.PC)
+	record.Attrs(func(a slog.Attr) bool {
+		redacted.AddAttrs(h.redactor.Attr(a))
+		return true
+	})
+	return h.next.Handle(ctx, redacted)
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		redacted[i] = h.redactor.Attr(a)
+	}
+	return &handler{next: h.next.WithAttrs(redacted), redactor: h.redactor}
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	return &handler{next: h.next.WithGroup(name), redactor: h.redactor}
+}
+
+// Attr masks the value of a.
+func (r *Redactor) Attr(a slog.Attr) slog.Attr {
+	value := a.Value.Resolve()
+	switch value.Kind() {
+	case slog.KindString:
+		return slog.Any(a.Key, r.Value(a.Key, value.String()))
+	case slog.KindAny:
+		return slog.Any(a.Key, r.Value(a.Key, value.Any()))
+	case slog.KindGroup:
+		attrs := value.Group()
+		redacted := make([]any, len(attrs))
+		for i, attr := range attrs {
+			redacted[i] = r.Attr(attr)
+		}
+		return slog.Group(a.Key, redacted...)
+	}
+	return a
+}
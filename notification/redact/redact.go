@@ -0,0 +1,222 @@
+// Package redact masks personal data and secrets before they are logged.
+// Phone numbers and email addresses are masked wherever they appear, and
+// the values of sensitive fields, such as phone_number or authorization,
+// are masked in log attributes and in structured payloads.
+//
+// Phone numbers are recognized in text when they parse with the phone
+// package and are written in international format, or as national numbers
+// of phone.DefaultRegion with their trunk prefix or calling code, e.g.
+// "0812-3456-7890" or "+62 812 3456 7890". Other figures, such as IDs and
+// amounts, are left as is.
+//
+//	logger := slog.New(redact.NewHandler(handler, redact.New("content")))
+package redact
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/phone"
+)
+
+// Redacted replaces values that cannot be partially masked.
+const Redacted = "[REDACTED]"
+
+// DefaultFields are the field names masked by every Redactor.
+var DefaultFields = []string{
+	"phone", "phone_number", "phone_numbers", "recipient", "to", "cc", "bcc", "email",
+	"api_key", "apikey", "appkey", "authkey", "authorization", "token", "password", "secret",
+}
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	// phonePattern matches runs of digit groups separated by spaces,
+	// dashes, dots or parentheses, which may hold phone numbers.
+	phonePattern  = regexp.MustCompile(`\+?\(?\b\d[\d ().\-]{6,}\d\b`)
+	bearerPattern = regexp.MustCompile(`(?i)\bbearer\s+\S+`)
+)
+
+// maxPhoneLength bounds the length of a formatted phone number, e.g.
+// "+62 (812) 3456-7890".
+const maxPhoneLength = 24
+
+type Redactor struct {
+	fields map[string]bool
+}
+
+// New returns a Redactor masking DefaultFields and fields. Field names are
+// compared case-insensitively.
+func New(fields ...string) *Redactor {
+	r := &Redactor{fields: make(map[string]bool, len(DefaultFields)+len(fields))}
+	for _, field := range append(append([]string(nil), DefaultFields...), fields...) {
+		r.fields[strings.ToLower(field)] = true
+	}
+	return r
+}
+
+// IsField reports whether the values of field are masked.
+func (r *Redactor) IsField(field string) bool {
+	return r.fields[strings.ToLower(field)]
+}
+
+// String masks the email addresses, phone numbers and bearer tokens in s.
+func (r *Redactor) String(s string) string {
+	s = bearerPattern.ReplaceAllString(s, "Bearer "+Redacted)
+	s = emailPattern.ReplaceAllStringFunc(s, Email)
+	return phonePattern.ReplaceAllStringFunc(s, maskPhones)
+}
+
+// Field masks value, the value of a sensitive field. Email addresses and
+// phone numbers are partially masked, anything else is replaced.
+func (r *Redactor) Field(value string) string {
+	if masked := r.String(value); masked != value {
+		return masked
+	}
+	if value == "" {
+		return value
+	}
+	return Redacted
+}
+
+// Value masks v, the value of key. Structs, maps and slices are walked
+// through their JSON form, masking the fields known to r and the personal
+// data found in strings.
+func (r *Redactor) Value(key string, v interface{}) interface{} {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case string:
+		if r.IsField(key) {
+			return r.Field(v)
+		}
+		return r.String(v)
+	case []byte:
+		return r.Value(key, string(v))
+	case error:
+		return r.Value(key, v.Error())
+	}
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return Redacted
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		return Redacted
+	}
+	return r.walk(r.IsField(key), decoded)
+}
+
+func (r *Redactor) walk(sensitive bool, v interface{}) interface{} {
+	switch v := v.(type) {
+	case string:
+		if sensitive {
+			return r.Field(v)
+		}
+		return r.String(v)
+	case []interface{}:
+		for i := range v {
+			v[i] = r.walk(sensitive, v[i])
+		}
+		return v
+	case map[string]interface{}:
+		for key, value := range v {
+			v[key] = r.walk(sensitive || r.IsField(key), value)
+		}
+		return v
+	}
+	return v
+}
+
+// Email masks the local part of an email address but its first character,
+// or entirely when it is shorter than three characters.
+func Email(address string) string {
+	at := strings.LastIndex(address, "@")
+	switch {
+	case at <= 0:
+		return Redacted
+	case at < 3:
+		return strings.Repeat("*", at) + address[at:]
+	}
+	return address[:1] + strings.Repeat("*", at-1) + address[at:]
+}
+
+// Phone masks a phone number but its last four digits.
+func Phone(phoneNumber string) string {
+	runes := []rune(phoneNumber)
+	for i := 0; i < len(runes)-4; i++ {
+		if runes[i] >= '0' && runes[i] <= '9' {
+			runes[i] = '*'
+		}
+	}
+	if len(runes) <= 4 {
+		return strings.Repeat("*", len(runes))
+	}
+	return string(runes)
+}
+
+// maskPhones masks the phone numbers in run, a match of phonePattern that
+// may hold several numbers or numbers next to other figures.
+func maskPhones(run string) string {
+	var b strings.Builder
+	for rest := run; rest != ""; {
+		if n := phoneLength(rest); n > 0 {
+			b.WriteString(Phone(rest[:n]))
+			rest = rest[n:]
+			continue
+		}
+		n := nextGroup(rest)
+		b.WriteString(rest[:n])
+		rest = rest[n:]
+	}
+	return b.String()
+}
+
+// phoneLength returns the length of the longest phone number at the start
+// of s ending with a digit group, or 0.
+func phoneLength(s string) int {
+	for end := min(len(s), maxPhoneLength); end > 0; end-- {
+		if isDigit(s[end-1]) && (end == len(s) || !isDigit(s[end])) && isPhone(s[:end]) {
+			return end
+		}
+	}
+	return 0
+}
+
+// nextGroup returns the start of the digit group following the first one
+// in s.
+func nextGroup(s string) int {
+	i := 0
+	for i < len(s) && !isDigit(s[i]) {
+		i++
+	}
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	for i < len(s) && !isDigit(s[i]) {
+		i++
+	}
+	return i
+}
+
+// isPhone reports whether s parses as a phone number written with its
+// international prefix, trunk prefix or calling code. Bare national
+// numbers are not told apart from other figures.
+func isPhone(s string) bool {
+	s = strings.TrimSpace(s)
+	number, err := phone.Parse(s, phone.DefaultRegion)
+	if err != nil {
+		return false
+	}
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, s)
+	return strings.HasPrefix(s, "+") || number.National != digits
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
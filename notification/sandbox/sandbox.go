@@ -0,0 +1,178 @@
+// Package sandbox implements the dry-run mode of the gateways. A gateway in
+// sandbox mode validates and renders its payload as usual, then hands what
+// it would have sent to a Store instead of delivering it, and returns a
+// realistic fake result.
+package sandbox
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	cfg "github.com/DamiaRalitsa/notif-lib-golang/notification/config"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/phone"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/redact"
+)
+
+// Channels recorded in Record.Channel.
+const (
+	ChannelBell     = "bell"
+	ChannelEmail    = "email"
+	ChannelOCA      = "oca"
+	ChannelWhatsapp = "whatsapp"
+)
+
+// Record is a message that would have been sent.
+type Record struct {
+	Channel  string
+	Endpoint string
+	// Recipients are the recipients after rewriting.
+	Recipients []string
+	// Payload is the rendered request: a JSON value for HTTP gateways and
+	// the raw MIME message for SMTP.
+	Payload interface{}
+	Time    time.Time
+}
+
+type Store interface {
+	Store(ctx context.Context, record Record) error
+}
+
+// MemoryStore keeps records in memory.
+type MemoryStore struct {
+	mu      sync.Mutex
+	records []Record
+}
+
+func (s *MemoryStore) Store(ctx context.Context, record Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, record)
+	return nil
+}
+
+// Records returns the stored records.
+func (s *MemoryStore) Records() []Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Record(nil), s.records...)
+}
+
+// LogStore logs records instead of storing them, masking personal data.
+type LogStore struct {
+	// Logger defaults to slog.Default().
+	Logger *slog.Logger
+	// Redactor defaults to redact.New().
+	Redactor *redact.Redactor
+}
+
+func (s LogStore) Store(ctx context.Context, record Record) error {
+	logger := s.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	logger = slog.New(redact.NewHandler(logger.Handler(), s.Redactor))
+	logger.InfoContext(ctx, "sandbox message captured", "channel", record.Channel, "endpoint", record.Endpoint, "recipient", record.Recipients, "payload", record.Payload)
+	return nil
+}
+
+type Sandbox struct {
+	// Store receives what would have been sent. Defaults to a LogStore
+	// writing to the logger of the gateway.
+	Store Store
+	// CatchAllEmail replaces every email recipient when set.
+	CatchAllEmail string
+	// TestPhoneNumbers are the only numbers messages may be addressed to.
+	// Other numbers are rewritten to the first test number.
+	TestPhoneNumbers []string
+	// TestUserID replaces the user of every bell notification when set.
+	TestUserID string
+	// PhoneRegion is the region of test numbers given without country
+	// code. Defaults to phone.DefaultRegion.
+	PhoneRegion string
+}
+
+// FromEnv returns the sandbox configured by the NOTIF_SANDBOX* variables,
+// or nil when sandbox mode is disabled. An invalid configuration is an
+// error rather than a disabled sandbox, so that it never turns into live
+// sending.
+func FromEnv() (*Sandbox, error) {
+	config, err := cfg.InitEnv(cfg.SANDBOX)
+	if err != nil {
+		return nil, err
+	}
+	if !config.SandboxConfig.Enabled {
+		return nil, nil
+	}
+	s := &Sandbox{
+		CatchAllEmail: config.SandboxConfig.CatchAllEmail,
+		TestUserID:    config.SandboxConfig.TestUserID,
+	}
+	for _, number := range strings.Split(config.SandboxConfig.TestPhoneNumbers, ",") {
+		if number = strings.TrimSpace(number); number != "" {
+			s.TestPhoneNumbers = append(s.TestPhoneNumbers, number)
+		}
+	}
+	return s, nil
+}
+
+// Capture stores record in place of sending it.
+func (s *Sandbox) Capture(ctx context.Context, record Record) error {
+	if record.Time.IsZero() {
+		record.Time = time.Now()
+	}
+	store := s.Store
+	if store == nil {
+		store = LogStore{}
+	}
+	return store.Store(ctx, record)
+}
+
+// Phone rewrites a phone number that is not a test number to the first
+// test number, in the digits-only form the gateways send.
+func (s *Sandbox) Phone(phoneNumber string) string {
+	if len(s.TestPhoneNumbers) == 0 {
+		return phoneNumber
+	}
+	for _, allowed := range s.TestPhoneNumbers {
+		if phone.Equal(allowed, phoneNumber, s.region()) {
+			return phoneNumber
+		}
+	}
+	if number, err := phone.Parse(s.TestPhoneNumbers[0], s.region()); err == nil {
+		return number.Digits()
+	}
+	return s.TestPhoneNumbers[0]
+}
+
+func (s *Sandbox) region() string {
+	if s.PhoneRegion == "" {
+		return phone.DefaultRegion
+	}
+	return s.PhoneRegion
+}
+
+// Phones rewrites phone numbers with Phone, dropping the resulting
+// duplicates.
+func (s *Sandbox) Phones(phoneNumbers []string) []string {
+	seen := make(map[string]bool, len(phoneNumbers))
+	rewritten := make([]string, 0, len(phoneNumbers))
+	for _, phoneNumber := range phoneNumbers {
+		phoneNumber = s.Phone(phoneNumber)
+		if !seen[phoneNumber] {
+			seen[phoneNumber] = true
+			rewritten = append(rewritten, phoneNumber)
+		}
+	}
+	return rewritten
+}
+
+// UserID rewrites a bell user ID to the test user.
+func (s *Sandbox) UserID(userID string) string {
+	if s.TestUserID == "" {
+		return userID
+	}
+	return s.TestUserID
+}
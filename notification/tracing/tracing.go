@@ -0,0 +1,130 @@
+// Package tracing creates the OpenTelemetry spans of the gateways. Every
+// send gets a span, with child spans per recipient, HTTP request and SMTP
+// session, and HTTP requests carry the W3C traceparent header of the
+// context passed to the send. Span attributes and errors are masked with a
+// redact.Redactor.
+package tracing
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/redact"
+)
+
+// InstrumentationName names the tracer of the library.
+const InstrumentationName = "github.com/DamiaRalitsa/notif-lib-golang/notification"
+
+// Attribute keys set on the spans.
+const (
+	ChannelKey     = attribute.Key("notification.channel")
+	RecipientsKey  = attribute.Key("notification.recipients")
+	RecipientKey   = attribute.Key("notification.recipient")
+	TemplateKey    = attribute.Key("notification.template")
+	AttachmentsKey = attribute.Key("notification.attachments")
+)
+
+type Tracer struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+	redactor   *redact.Redactor
+}
+
+// New returns a Tracer creating spans with provider and injecting trace
+// context with propagator. Nil arguments default to otel.GetTracerProvider(),
+// propagation.TraceContext and redact.New().
+func New(provider trace.TracerProvider, propagator propagation.TextMapPropagator, redactor *redact.Redactor) *Tracer {
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	if propagator == nil {
+		propagator = propagation.TraceContext{}
+	}
+	if redactor == nil {
+		redactor = redact.New()
+	}
+	return &Tracer{
+		tracer:     provider.Tracer(InstrumentationName),
+		propagator: propagator,
+		redactor:   redactor,
+	}
+}
+
+// Start starts a span of channel.
+func (t *Tracer) Start(ctx context.Context, name, channel string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return t.tracer.Start(ctx, name, trace.WithAttributes(append(attrs, ChannelKey.String(channel))...))
+}
+
+// StartClient starts a client span, for a call to a remote server.
+func (t *Tracer) StartClient(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return t.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+// StartRecipient starts the span of the delivery to recipient, masked with
+// redact.Phone or redact.Email.
+func (t *Tracer) StartRecipient(ctx context.Context, name, recipient string) (context.Context, trace.Span) {
+	return t.tracer.Start(ctx, name, trace.WithAttributes(RecipientKey.String(t.redactor.Field(recipient))))
+}
+
+// End records err on span, masked, and ends it.
+func (t *Tracer) End(span trace.Span, err error) {
+	if err != nil {
+		message := t.redactor.String(err.Error())
+		span.AddEvent("exception", trace.WithAttributes(
+			attribute.String("exception.type", "error"),
+			attribute.String("exception.message", message),
+		))
+		span.SetStatus(codes.Error, message)
+	}
+	span.End()
+}
+
+// Inject writes the trace context of ctx into header.
+func (t *Tracer) Inject(ctx context.Context, header http.Header) {
+	t.propagator.Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+// Client returns a copy of client whose requests get a client span and the
+// traceparent header of their context.
+func (t *Tracer) Client(client *http.Client) *http.Client {
+	traced := *client
+	traced.Transport = &transport{next: client.Transport, tracer: t}
+	return &traced
+}
+
+type transport struct {
+	next   http.RoundTripper
+	tracer *Tracer
+}
+
+func (rt *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := rt.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	ctx, span := rt.tracer.StartClient(req.Context(), req.Method,
+		attribute.String("http.request.method", req.Method),
+		attribute.String("url.full", req.URL.Redacted()),
+		attribute.String("server.address", req.URL.Hostname()),
+	)
+	req = req.Clone(ctx)
+	rt.tracer.Inject(ctx, req.Header)
+
+	resp, err := next.RoundTrip(req)
+	if err != nil {
+		rt.tracer.End(span, err)
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	if resp.StatusCode >= 400 {
+		span.SetStatus(codes.Error, resp.Status)
+	}
+	span.End()
+	return resp, nil
+}
@@ -0,0 +1,182 @@
+// Package templates maps the Type of a WhatsApp message to its text, so
+// new message types are added without a release of the library.
+//
+// Templates are text/template sources, added in code or read from an fs.FS
+// laid out as:
+//
+//	<type>/message.txt           default text
+//	<type>/<locale>/message.txt  per-locale overrides, e.g. PO/en/message.txt
+//
+// Templates are executed with the Data of the message and its ID, e.g.
+// "Order {{.ID}} is ready, {{.name}}". Every catalog starts with the
+// built-in "PO" and "customer" messages, which files and Add override.
+package templates
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+const messageFile = "message.txt"
+
+var ErrTemplateNotFound = errors.New("whatsapp template not found")
+
+// builtin are the messages the gateway used to hardcode.
+var builtin = map[string]string{
+	"PO":       "Halo, Selamat kamu mendapatkan pesanan baru dengan nomor pesanan {{.ID}}.\n\nSilahkan cek aplikasi untuk melihat detail pesanan.\n\nTerima kasih.",
+	"customer": "Halo, terima kasih telah melakukan pemesanan dengan nomor pesanan {{.ID}}.\n\nPesanan akan segera kami proses. Mohon ditunggu.\n\nTerima kasih.",
+}
+
+type Catalog struct {
+	fsys fs.FS
+
+	mu      sync.RWMutex
+	added   map[string]*template.Template
+	files   map[string]*template.Template
+	builtin map[string]*template.Template
+}
+
+// New creates a catalog holding the built-in messages only.
+func New() *Catalog {
+	return NewFromFS(nil)
+}
+
+// NewFromFS creates a catalog reading templates from fsys, such as an
+// embed.FS.
+func NewFromFS(fsys fs.FS) *Catalog {
+	c := &Catalog{
+		fsys:    fsys,
+		added:   make(map[string]*template.Template),
+		files:   make(map[string]*template.Template),
+		builtin: make(map[string]*template.Template),
+	}
+	for messageType, text := range builtin {
+		c.builtin[key(messageType, "")] = template.Must(parse(messageType, text))
+	}
+	return c
+}
+
+// NewFromDir creates a catalog reading templates from a directory.
+func NewFromDir(dir string) (*Catalog, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("template path %s is not a directory", dir)
+	}
+	return NewFromFS(os.DirFS(dir)), nil
+}
+
+// Add sets the text of messageType in locale, or its default text when
+// locale is empty.
+func (c *Catalog) Add(messageType, locale, text string) error {
+	t, err := parse(messageType, text)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.added[key(messageType, normalizeLocale(locale))] = t
+	return nil
+}
+
+// Render returns the text of messageType filled with data. Locale texts are
+// preferred in the order "en-US", "en", then the default text. It fails
+// with ErrTemplateNotFound when messageType has no text, and when data
+// misses a placeholder.
+func (c *Catalog) Render(messageType, locale string, data map[string]string) (string, error) {
+	t, err := c.lookup(messageType, locale)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("render whatsapp template %s: %w", messageType, err)
+	}
+	return buf.String(), nil
+}
+
+func (c *Catalog) lookup(messageType, locale string) (*template.Template, error) {
+	if messageType == "" || strings.Contains(messageType, "..") || !fs.ValidPath(messageType) {
+		return nil, fmt.Errorf("%w: %q", ErrTemplateNotFound, messageType)
+	}
+	for _, locale := range locales(locale) {
+		k := key(messageType, locale)
+		c.mu.RLock()
+		t, added := c.added[k]
+		file, loaded := c.files[k]
+		c.mu.RUnlock()
+		if added {
+			return t, nil
+		}
+		if !loaded {
+			var err error
+			if file, err = c.load(messageType, locale); err != nil {
+				return nil, err
+			}
+		}
+		if file != nil {
+			return file, nil
+		}
+		if t, ok := c.builtin[k]; ok {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrTemplateNotFound, messageType)
+}
+
+// load parses the file of messageType in locale, returning nil when there
+// is none. Missing files are remembered as nil.
+func (c *Catalog) load(messageType, locale string) (*template.Template, error) {
+	var t *template.Template
+	if c.fsys != nil {
+		p := path.Join(messageType, locale, messageFile)
+		src, err := fs.ReadFile(c.fsys, p)
+		switch {
+		case err == nil:
+			if t, err = parse(p, strings.TrimRight(string(src), "\n")); err != nil {
+				return nil, err
+			}
+		case !errors.Is(err, fs.ErrNotExist):
+			return nil, err
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.files[key(messageType, locale)] = t
+	return t, nil
+}
+
+func parse(name, text string) (*template.Template, error) {
+	return template.New(name).Option("missingkey=error").Parse(text)
+}
+
+// locales lists the locales searched for a template, most specific first,
+// ending with the default "".
+func locales(locale string) []string {
+	locale = normalizeLocale(locale)
+	list := make([]string, 0, 3)
+	if locale != "" && fs.ValidPath(locale) && !strings.Contains(locale, "/") {
+		list = append(list, locale)
+		if lang, _, ok := strings.Cut(locale, "-"); ok {
+			list = append(list, lang)
+		}
+	}
+	return append(list, "")
+}
+
+func normalizeLocale(locale string) string {
+	return strings.ReplaceAll(locale, "_", "-")
+}
+
+func key(messageType, locale string) string {
+	return messageType + "\x00" + locale
+}
@@ -2,10 +2,16 @@ package whatsapp
 
 // Whatsapp represents the structure of the WhatsApp message.
 type Whatsapp struct {
-	To      []string `json:"to"`
-	Type    string   `json:"type"`
-	ID      string   `json:"id"`
-	Message string   `json:"message"`
+	To []string `json:"to"`
+	// Type selects the text of the message from the template catalog of
+	// the gateway. Message is sent as is when Type has no template.
+	Type    string `json:"type"`
+	ID      string `json:"id"`
+	Message string `json:"message"`
+	// Locale selects a locale variant of the template, e.g. "en".
+	Locale string `json:"locale,omitempty"`
+	// Data fills the placeholders of the template, besides ID.
+	Data map[string]string `json:"data,omitempty"`
 }
 
 type Respond struct {
@@ -4,9 +4,21 @@ import (
 	"bytes"
 	"context"
 	"errors"
-	"log"
+	"log/slog"
 	"mime/multipart"
 	"net/http"
+	"time"
+
+	cfg "github.com/DamiaRalitsa/notif-lib-golang/notification/config"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/httpstatus"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/internal/envprefix"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/internal/report"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/metrics"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/option"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/phone"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/sandbox"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/tracing"
+	"github.com/DamiaRalitsa/notif-lib-golang/notification/whatsapp/templates"
 )
 
 type WhatsappHandler struct {
@@ -21,100 +33,182 @@ type WhatsappConfig struct {
 
 // Gateway represents the structure of the gateway.
 type gateway struct {
-	BaseURL string
-	AppKey  string
-	AuthKey string
-	// HttpClient *helpers.ToolsAPI
+	BaseURL     string
+	AppKey      string
+	AuthKey     string
+	HTTPClient  *http.Client
+	Sandbox     *sandbox.Sandbox
+	PhoneRegion string
+	Templates   *templates.Catalog
+	Logger      *slog.Logger
+	Metrics     metrics.Metrics
+	Tracer      *tracing.Tracer
+}
+
+// NewWhatsappEnvHandler creates a WhatsappClient configured from the
+// NOTIF_WHATSAPP_* environment variables. Templates are read from
+// NOTIF_WHATSAPP_TEMPLATE_DIR when it is set and no catalog is given.
+func NewWhatsappEnvHandler(opts ...option.Option) (WhatsappClient, error) {
+	config, err := cfg.InitEnv(cfg.WHATSAPP)
+	if err != nil {
+		return nil, err
+	}
+	o, err := option.New(opts...)
+	if err != nil {
+		return nil, err
+	}
+	if o.WhatsappTemplates == nil && config.WhatsappConfig.TemplateDir != "" {
+		if o.WhatsappTemplates, err = templates.NewFromDir(config.WhatsappConfig.TemplateDir); err != nil {
+			return nil, err
+		}
+	}
+	return newGateway(WhatsappConfig{
+		BaseURL: config.WhatsappConfig.BaseURL,
+		AppKey:  config.WhatsappConfig.AppKey,
+		AuthKey: config.WhatsappConfig.AuthKey,
+	}, o), nil
+}
+
+// NewWhatsappHandler creates a new WhatsappHandler instance from
+// whatsappConfig alone. Unlike NewWhatsappEnvHandler it does not read the
+// environment, so sandbox mode is only enabled by option.WithSandbox.
+func NewWhatsappHandler(whatsappConfig WhatsappConfig, opts ...option.Option) WhatsappClient {
+	return newGateway(whatsappConfig, option.NewWithoutEnv(opts...))
 }
 
-// NewWhatsappHandler creates a new WhatsappHandler instance.
-func NewWhatsappHandler(whatsappConfig WhatsappConfig) WhatsappClient {
-	g := &gateway{
-		BaseURL: whatsappConfig.BaseURL,
-		AppKey:  whatsappConfig.AppKey,
-		AuthKey: whatsappConfig.AuthKey,
+func newGateway(whatsappConfig WhatsappConfig, o option.Options) *gateway {
+	if o.WhatsappTemplates == nil {
+		o.WhatsappTemplates = templates.New()
+	}
+	return &gateway{
+		BaseURL:     o.BaseURLOr(whatsappConfig.BaseURL),
+		AppKey:      whatsappConfig.AppKey,
+		AuthKey:     whatsappConfig.AuthKey,
+		HTTPClient:  o.HTTPClient,
+		Sandbox:     o.Sandbox,
+		PhoneRegion: o.PhoneRegion,
+		Templates:   o.WhatsappTemplates,
+		Logger:      o.Logger,
+		Metrics:     o.Metrics,
+		Tracer:      o.Tracer,
 	}
-	return g
 }
 
 // SendWhatsapp sends a WhatsApp message to multiple phone numbers.
 func (g gateway) SendWhatsapp(ctx context.Context, body Whatsapp) (data interface{}, err error) {
+	ctx, span := g.Tracer.Start(ctx, "whatsapp.SendWhatsapp", sandbox.ChannelWhatsapp, tracing.RecipientsKey.Int(len(body.To)))
+	start := time.Now()
 	url := g.BaseURL
+	defer func() {
+		g.Tracer.End(span, err)
+		report.Send(ctx, g.Logger, g.Metrics, sandbox.ChannelWhatsapp, url, len(body.To), start, err)
+	}()
+
+	if body.Type != "" {
+		message, err := g.Templates.Render(body.Type, body.Locale, templateData(body))
+		switch {
+		case err == nil:
+			body.Message = message
+		case !errors.Is(err, templates.ErrTemplateNotFound):
+			return nil, metrics.Invalid(err)
+		}
+	}
+	body.Message = envprefix.Apply(ctx, body.Message)
 
 	for _, phoneNumber := range body.To {
-		// Create a buffer
-		buf := new(bytes.Buffer)
-		writer := multipart.NewWriter(buf)
-
-		checkPhoneNumber := phoneNumber[:2]
-		if checkPhoneNumber == "08" {
-			phoneNumber = "62" + phoneNumber[1:]
-
-		} else if checkPhoneNumber == "+6" {
-			phoneNumber = phoneNumber[1:]
-		} else if checkPhoneNumber != "62" {
-			return nil, errors.New("Invalid phone number")
+		if err := g.sendTo(ctx, url, phoneNumber, body.Message); err != nil {
+			return nil, err
 		}
+	}
 
-		log.Println("WHATSAPP SENT TO PHONE NUMBER:", phoneNumber)
+	response := map[string]interface{}{
+		"message": "Whatsapp sent successfully",
+		"status":  "success",
+	}
 
-		if body.Type == "PO" {
-			body.Message = "Halo, Selamat kamu mendapatkan pesanan baru dengan nomor pesanan " + body.ID + ".\n\nSilahkan cek aplikasi untuk melihat detail pesanan.\n\nTerima kasih."
-		}
-		if body.Type == "customer" {
-			body.Message = "Halo, terima kasih telah melakukan pemesanan dengan nomor pesanan " + body.ID + ".\n\nPesanan akan segera kami proses. Mohon ditunggu.\n\nTerima kasih."
-		}
+	return response, nil
+}
 
-		// Write the fields
-		_ = writer.WriteField("appkey", g.AppKey)
-		_ = writer.WriteField("authkey", g.AuthKey)
-		_ = writer.WriteField("to", phoneNumber)
-		_ = writer.WriteField("message", body.Message)
+// templateData returns the values of the placeholders of body.
+func templateData(body Whatsapp) map[string]string {
+	data := map[string]string{"ID": body.ID}
+	for name, value := range body.Data {
+		data[name] = value
+	}
+	return data
+}
 
-		// Close the writer
-		err = writer.Close()
-		if err != nil {
-			return nil, err
-		}
+// sendTo sends message to a single phone number.
+func (g gateway) sendTo(ctx context.Context, url, phoneNumber, message string) (err error) {
+	ctx, span := g.Tracer.StartRecipient(ctx, "whatsapp.SendWhatsapp.recipient", phoneNumber)
+	defer func() {
+		g.Tracer.End(span, err)
+	}()
 
-		// Create a new request
-		req, err := http.NewRequest("POST", url, buf)
-		if err != nil {
-			return nil, err
-		}
+	// Create a buffer
+	buf := new(bytes.Buffer)
+	writer := multipart.NewWriter(buf)
 
-		// Set the content type
-		req.Header.Set("Content-Type", writer.FormDataContentType())
+	number, err := phone.Parse(phoneNumber, g.PhoneRegion)
+	if err != nil {
+		return metrics.Invalid(err)
+	}
+	phoneNumber = number.Digits()
+
+	g.Logger.DebugContext(ctx, "sending whatsapp", "channel", sandbox.ChannelWhatsapp, "recipient", phoneNumber)
+
+	if g.Sandbox != nil {
+		phoneNumber = g.Sandbox.Phone(phoneNumber)
+		return g.Sandbox.Capture(ctx, sandbox.Record{
+			Channel:    sandbox.ChannelWhatsapp,
+			Endpoint:   url,
+			Recipients: []string{phoneNumber},
+			Payload:    map[string]string{"to": phoneNumber, "message": message},
+		})
+	}
 
-		// Send the request
-		res, err := http.DefaultClient.Do(req)
-		if err != nil {
-			log.Println("res.Body is not a map")
-			log.Println("PATH:", url)
-			log.Println("STATUS:", res.StatusCode)
-			log.Println("MESSAGE:", res.Status)
-			return nil, err
-		}
-		defer res.Body.Close()
+	// Write the fields
+	_ = writer.WriteField("appkey", g.AppKey)
+	_ = writer.WriteField("authkey", g.AuthKey)
+	_ = writer.WriteField("to", phoneNumber)
+	_ = writer.WriteField("message", message)
+
+	// Close the writer
+	err = writer.Close()
+	if err != nil {
+		return err
 	}
 
-	response := map[string]interface{}{
-		"message": "Whatsapp sent successfully",
-		"status":  "success",
+	// Create a new request
+	req, err := http.NewRequestWithContext(ctx, "POST", url, buf)
+	if err != nil {
+		return err
 	}
 
-	return response, nil
+	// Set the content type
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	// Send the request
+	res, err := g.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	g.Logger.DebugContext(ctx, "response from external endpoint", "channel", sandbox.ChannelWhatsapp, "endpoint", url, "status", res.StatusCode)
+	return httpstatus.Check(res, nil)
 }
 
 func (g *gateway) NewWhatsappClient() WhatsappClient {
-	// baseUrl := os.Getenv("WHATSAPP_BASE_URL")
-	// appKey := os.Getenv("WHATSAPP_APP_KEY")
-	// authKey := os.Getenv("WHATSAPP_AUTH_KEY")
-	// httpClient := &helpers.ToolsAPI{}
 	return &gateway{
-		BaseURL: g.BaseURL,
-		AppKey:  g.AppKey,
-		AuthKey: g.AuthKey,
-		// HttpClient: httpClient,
+		BaseURL:     g.BaseURL,
+		AppKey:      g.AppKey,
+		AuthKey:     g.AuthKey,
+		HTTPClient:  g.HTTPClient,
+		Sandbox:     g.Sandbox,
+		PhoneRegion: g.PhoneRegion,
+		Templates:   g.Templates,
+		Logger:      g.Logger,
+		Metrics:     g.Metrics,
+		Tracer:      g.Tracer,
 	}
 }